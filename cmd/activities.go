@@ -19,13 +19,10 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/activities", nil)
-			if err != nil {
-				return err
-			}
-			return printJSON(data)
+			return listPages(cmd, c, "/v1/activities", nil)
 		},
 	}
+	addPageFlags(ls)
 
 	parent.AddCommand(ls)
 	rootCmd.AddCommand(parent)
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	api := &cobra.Command{
+		Use:   "api <path>",
+		Short: "Make an authenticated App API request",
+		Long: `Make an authenticated request to the App API and print the response.
+
+The path is relative to the region's base URL, e.g. /v1/segments. The
+method defaults to GET, or POST when fields or a body are given without
+--paginate.
+
+Fields are sent as query parameters for GET and as a JSON object body
+otherwise:
+  -f key=value   string value
+  -F key=value   typed value: true, false, null and integers become JSON
+                 literals; @file reads the value from a file, @- from stdin
+Use key[sub]=value for nested objects and key[]=value to build arrays.`,
+		Example: `  cio api /v1/segments
+  cio api /v1/messages -f type=email --paginate --jq '.messages[].id'
+  cio api -X PUT /v1/snippets -f name=footer -F value=@footer.html
+  cio api -X DELETE /v1/segments/12`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			method, _ := cmd.Flags().GetString("method")
+			rawFields, _ := cmd.Flags().GetStringArray("raw-field")
+			typedFields, _ := cmd.Flags().GetStringArray("field")
+			headers, _ := cmd.Flags().GetStringArray("header")
+			paginate, _ := cmd.Flags().GetBool("paginate")
+
+			path, query, err := splitAPIPath(args[0])
+			if err != nil {
+				return err
+			}
+			hasFields := len(rawFields)+len(typedFields) > 0
+			method = strings.ToUpper(method)
+			if method == "" {
+				method = http.MethodGet
+				if (hasFields || bodyGiven(cmd)) && !paginate {
+					method = http.MethodPost
+				}
+			}
+			if paginate && method != http.MethodGet {
+				return fmt.Errorf("--paginate requires GET")
+			}
+
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			for _, h := range headers {
+				k, v, ok := strings.Cut(h, ":")
+				if !ok || strings.TrimSpace(k) == "" {
+					return fmt.Errorf("invalid header %q (want 'Key: value')", h)
+				}
+				if c.Header == nil {
+					c.Header = make(http.Header)
+				}
+				c.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+			}
+
+			var body io.Reader
+			if method == http.MethodGet || method == http.MethodHead {
+				for _, f := range rawFields {
+					k, v, err := splitField(f)
+					if err != nil {
+						return err
+					}
+					query.Add(k, v)
+				}
+				for _, f := range typedFields {
+					k, v, err := splitField(f)
+					if err != nil {
+						return err
+					}
+					val, err := typedValue(v)
+					if err != nil {
+						return err
+					}
+					if val == nil {
+						val = ""
+					}
+					query.Add(k, fmt.Sprint(val))
+				}
+			} else {
+				raw, err := readBody(cmd)
+				if err != nil {
+					return err
+				}
+				if raw != nil && hasFields {
+					return fmt.Errorf("fields cannot be combined with a request body")
+				}
+				if hasFields {
+					obj, err := buildFields(rawFields, typedFields)
+					if err != nil {
+						return err
+					}
+					if raw, err = json.Marshal(obj); err != nil {
+						return err
+					}
+				}
+				if raw != nil {
+					body = bytes.NewReader(raw)
+				}
+			}
+
+			if paginate {
+				return fetchPages(cmd.Context(), c, path, query, pageOptions{all: true})
+			}
+			data, err := c.Do(cmd.Context(), method, path, query, body)
+			if err != nil {
+				return err
+			}
+			if !json.Valid(data) {
+				_, err := os.Stdout.Write(data)
+				return err
+			}
+			return printJSON(data)
+		},
+	}
+	api.Flags().StringP("method", "X", "", "HTTP method (default GET, or POST with fields or a body)")
+	api.Flags().StringArrayP("raw-field", "f", nil, "Add a string parameter as key=value")
+	api.Flags().StringArrayP("field", "F", nil, "Add a typed parameter as key=value (@file reads a file)")
+	api.Flags().StringArrayP("header", "H", nil, "Add a request header as 'Key: value'")
+	api.Flags().Bool("paginate", false, "Follow next cursors and merge every page (GET only)")
+	addBodyFlag(api)
+
+	rootCmd.AddCommand(api)
+}
+
+// splitAPIPath separates an API path from any query string it carries.
+func splitAPIPath(p string) (string, url.Values, error) {
+	if strings.Contains(p, "://") {
+		return "", nil, fmt.Errorf("pass a path such as /v1/segments, not a full URL")
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	path, rawQuery, _ := strings.Cut(p, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid query string: %w", err)
+	}
+	return path, query, nil
+}
+
+func splitField(f string) (string, string, error) {
+	k, v, ok := strings.Cut(f, "=")
+	if !ok || k == "" {
+		return "", "", fmt.Errorf("invalid field %q (want key=value)", f)
+	}
+	return k, v, nil
+}
+
+// typedValue converts a -F value: JSON literals and integers keep their
+// type, @file and @- read the value from a file or stdin.
+func typedValue(v string) (any, error) {
+	switch v {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if file, ok := strings.CutPrefix(v, "@"); ok {
+		data, err := readFileArg(file)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return json.Number(v), nil
+	}
+	return v, nil
+}
+
+// buildFields assembles -f and -F fields into a JSON object.
+func buildFields(raw, typed []string) (map[string]any, error) {
+	obj := make(map[string]any)
+	for _, f := range raw {
+		k, v, err := splitField(f)
+		if err != nil {
+			return nil, err
+		}
+		if err := setField(obj, k, v); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range typed {
+		k, v, err := splitField(f)
+		if err != nil {
+			return nil, err
+		}
+		val, err := typedValue(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := setField(obj, k, val); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+// setField stores value under a key like a[b][c] or a[b][], creating
+// nested objects as needed; a trailing [] appends to an array.
+func setField(obj map[string]any, key string, value any) error {
+	name, rest, nested := strings.Cut(key, "[")
+	parts := []string{name}
+	if nested {
+		if !strings.HasSuffix(rest, "]") {
+			return fmt.Errorf("invalid field key %q", key)
+		}
+		parts = append(parts, strings.Split(strings.TrimSuffix(rest, "]"), "][")...)
+	}
+
+	cur := obj
+	for i, part := range parts {
+		last := i == len(parts)-1
+		switch {
+		case part == "" && (!last || i == 0):
+			return fmt.Errorf("invalid field key %q", key)
+		case last:
+			cur[part] = value
+			return nil
+		case parts[i+1] == "" && i+1 == len(parts)-1:
+			arr, _ := cur[part].([]any)
+			if cur[part] != nil && arr == nil {
+				return fmt.Errorf("field %q is not an array", key)
+			}
+			cur[part] = append(arr, value)
+			return nil
+		default:
+			next, ok := cur[part].(map[string]any)
+			if !ok {
+				if cur[part] != nil {
+					return fmt.Errorf("field %q conflicts with an earlier value", key)
+				}
+				next = make(map[string]any)
+				cur[part] = next
+			}
+			cur = next
+		}
+	}
+	return nil
+}
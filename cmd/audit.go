@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leechael/cio/internal/audit"
+	"github.com/leechael/cio/internal/client"
+	"github.com/leechael/cio/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	parent := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect the local audit log",
+		Long: "Every request that can change data is recorded with the OS user, profile,\n" +
+			"region, method, path, a hash of the body (or a redacted copy with\n" +
+			"audit_body=redacted), the response status and the command line.\n\n" +
+			"The log is $XDG_STATE_HOME/cio/audit.jsonl (default ~/.local/state) unless\n" +
+			"$CIO_AUDIT_LOG or the profile's audit_log names another file, \"syslog\"\n" +
+			"or \"off\".",
+	}
+
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print audit log entries",
+		Example: `  cio audit show --since 24h
+  cio audit show --since 2024-06-01 --jq '.[] | select(.status >= 400)'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := activeProfile()
+			if err != nil {
+				return err
+			}
+			dest, err := auditDestination(p)
+			if err != nil {
+				return err
+			}
+			switch dest {
+			case audit.Off:
+				return fmt.Errorf("the audit log is off")
+			case audit.Syslog:
+				return fmt.Errorf("the audit log is sent to syslog; query it there, e.g. journalctl -t cio")
+			}
+
+			var since time.Time
+			if s, _ := cmd.Flags().GetString("since"); s != "" {
+				if since, err = audit.ParseSince(s, time.Now()); err != nil {
+					return err
+				}
+			}
+			entries, err := audit.Read(dest, since)
+			if err != nil {
+				return err
+			}
+			if entries == nil {
+				entries = []audit.Entry{}
+			}
+			return printObject(entries)
+		},
+	}
+	show.Flags().String("since", "", "Only entries newer than a duration (24h, 7d) or a date")
+
+	parent.AddCommand(show)
+	rootCmd.AddCommand(parent)
+}
+
+// auditDestination resolves the audit log: $CIO_AUDIT_LOG, then the
+// profile's audit_log, then the default file.
+func auditDestination(p *config.Profile) (string, error) {
+	if dest := os.Getenv("CIO_AUDIT_LOG"); dest != "" {
+		return dest, nil
+	}
+	if p.AuditLog != "" {
+		return p.AuditLog, nil
+	}
+	return audit.DefaultPath()
+}
+
+// auditHook returns the client's Audit callback. The logger is opened on
+// the first request with side effects, so read-only commands never touch
+// the log. Failing to record is reported but does not fail the command:
+// the request has already been sent.
+func auditHook(p *config.Profile) (func(client.AuditRecord), error) {
+	dest, err := auditDestination(p)
+	if err != nil || dest == audit.Off {
+		return nil, err
+	}
+	var (
+		once    sync.Once
+		logger  audit.Logger
+		openErr error
+		osUser  string
+	)
+	command := strings.Join(redactArgs(os.Args), " ")
+	return func(rec client.AuditRecord) {
+		once.Do(func() {
+			osUser = os.Getenv("USER")
+			if u, err := user.Current(); err == nil {
+				osUser = u.Username
+			}
+			logger, openErr = audit.Open(dest)
+		})
+		if openErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: audit log: %v\n", openErr)
+			return
+		}
+		e := audit.Entry{
+			Time:    time.Now().UTC(),
+			User:    osUser,
+			Profile: p.Name,
+			Region:  p.Region,
+			Method:  rec.Method,
+			Path:    rec.Path,
+			Status:  rec.Status,
+			Command: command,
+		}
+		e.SetBody(rec.Body, p.AuditBody)
+		if rec.Err != nil {
+			e.Error = rec.Err.Error()
+		}
+		if err := logger.Log(e); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: audit log: %v\n", err)
+		}
+	}, nil
+}
+
+// auditSecretFlags carry request bodies or headers, which may hold personal
+// data or credentials; their values are left out of the recorded command.
+var auditSecretFlags = map[string]bool{
+	"--body": true, "--set": true, "--set-json": true, "--set-file": true,
+	"-f": true, "--raw-field": true, "-F": true, "--field": true,
+	"-H": true, "--header": true,
+}
+
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if name, _, ok := strings.Cut(arg, "="); ok && auditSecretFlags[name] {
+			arg = name + "=<redacted>"
+		}
+		out[i] = arg
+		if auditSecretFlags[arg] && i+1 < len(args) {
+			i++
+			out[i] = "<redacted>"
+		}
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/leechael/cio/internal/auth"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	parent := &cobra.Command{
+		Use:   "auth",
+		Short: "Store or remove the App API token in the OS keyring",
+	}
+
+	login := &cobra.Command{
+		Use:   "login",
+		Short: "Save an App API token for the active profile in the OS keyring",
+		Long: "Save an App API token for the active profile in the Secret Service keyring.\n" +
+			"The token is read from stdin when piped, otherwise prompted for without echo.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := activeProfile()
+			if err != nil {
+				return err
+			}
+			token, err := readSecret("App API token for profile " + p.Name + ": ")
+			if err != nil {
+				return err
+			}
+			if token == "" {
+				return fmt.Errorf("no token provided")
+			}
+			if err := auth.KeyringSet(p.Name, token); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Token saved to keyring for profile %s\n", p.Name)
+			return nil
+		},
+	}
+
+	logout := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove the active profile's token from the OS keyring",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := activeProfile()
+			if err != nil {
+				return err
+			}
+			if err := auth.KeyringDelete(p.Name); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Token removed from keyring for profile %s\n", p.Name)
+			return nil
+		},
+	}
+
+	parent.AddCommand(login, logout)
+	rootCmd.AddCommand(parent)
+}
+
+// readSecret reads one line from stdin. On a terminal it prompts on stderr
+// and turns off echo with stty so the token never appears on screen.
+func readSecret(prompt string) (string, error) {
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	if stty(os.Stdin, "-echo") == nil {
+		defer func() {
+			_ = stty(os.Stdin, "echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func stty(tty *os.File, arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = tty
+	return cmd.Run()
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+func addBodyFlag(cmd *cobra.Command) {
+	cmd.Flags().String("body", "", "JSON request body, or @file to read it from a file (@- for stdin)")
+	cmd.Flags().String("body-template", "", "Go template file rendered into the body; {{env \"NAME\"}} reads env vars")
+	cmd.Flags().StringArray("set", nil, "Set a body field as path.to.key=value; numbers, true, false and null are typed")
+	cmd.Flags().StringArray("set-json", nil, "Set a body field to raw JSON as path.to.key=json")
+	cmd.Flags().StringArray("set-file", nil, "Set a body field to a file's contents as path.to.key=@file")
+}
+
+// bodyGiven reports whether any body flag was used.
+func bodyGiven(cmd *cobra.Command) bool {
+	for _, name := range []string{"body", "body-template", "set", "set-json", "set-file"} {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// readBody assembles the request body. The base document comes from
+// --body-template, --body or piped stdin; --set, --set-json and --set-file
+// are then deep-merged into it in that order. It returns nil when no body
+// was given.
+func readBody(cmd *cobra.Command) (json.RawMessage, error) {
+	base, err := baseBody(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	sets, _ := cmd.Flags().GetStringArray("set")
+	setJSON, _ := cmd.Flags().GetStringArray("set-json")
+	setFile, _ := cmd.Flags().GetStringArray("set-file")
+	if len(sets)+len(setJSON)+len(setFile) == 0 {
+		return base, nil
+	}
+
+	doc := map[string]any{}
+	if base != nil {
+		v, err := decodeJSON(base)
+		if err != nil {
+			return nil, fmt.Errorf("body is not valid JSON: %w", err)
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("--set flags need the body to be a JSON object")
+		}
+		doc = obj
+	}
+
+	for _, s := range sets {
+		path, value, err := splitSet("--set", s)
+		if err != nil {
+			return nil, err
+		}
+		if err := setPath(doc, path, typedLiteral(value)); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range setJSON {
+		path, value, err := splitSet("--set-json", s)
+		if err != nil {
+			return nil, err
+		}
+		v, err := decodeJSON([]byte(value))
+		if err != nil {
+			return nil, fmt.Errorf("--set-json %s: %w", path, err)
+		}
+		if err := setPath(doc, path, v); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range setFile {
+		path, value, err := splitSet("--set-file", s)
+		if err != nil {
+			return nil, err
+		}
+		data, err := readFileArg(strings.TrimPrefix(value, "@"))
+		if err != nil {
+			return nil, err
+		}
+		if err := setPath(doc, path, string(data)); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(doc)
+}
+
+func baseBody(cmd *cobra.Command) (json.RawMessage, error) {
+	body, _ := cmd.Flags().GetString("body")
+	tmpl, _ := cmd.Flags().GetString("body-template")
+	if body != "" && tmpl != "" {
+		return nil, fmt.Errorf("--body and --body-template cannot be used together")
+	}
+	if tmpl != "" {
+		return renderBodyTemplate(tmpl)
+	}
+	if file, ok := strings.CutPrefix(body, "@"); ok {
+		return readFileArg(file)
+	}
+	if body != "" {
+		return json.RawMessage(body), nil
+	}
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > 0 {
+			return json.RawMessage(data), nil
+		}
+	}
+	return nil, nil
+}
+
+func requireBody(cmd *cobra.Command) (json.RawMessage, error) {
+	body, err := readBody(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, fmt.Errorf("request body is required (use -body flag or pipe via stdin)")
+	}
+	return body, nil
+}
+
+// readFileArg reads a file, or stdin for "-".
+func readFileArg(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// renderBodyTemplate executes a text/template file. Environment variables
+// are available as {{env "NAME"}} and {{.Env.NAME}}; {{json .Env.NAME}}
+// quotes a value as a JSON string.
+func renderBodyTemplate(file string) (json.RawMessage, error) {
+	src, err := readFileArg(file)
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	t, err := template.New(file).Option("missingkey=error").Funcs(template.FuncMap{
+		"env": os.Getenv,
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("body template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, map[string]any{"Env": env}); err != nil {
+		return nil, fmt.Errorf("body template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func splitSet(flag, s string) ([]string, string, error) {
+	path, value, ok := strings.Cut(s, "=")
+	if !ok || path == "" {
+		return nil, "", fmt.Errorf("invalid %s %q (want path.to.key=value)", flag, s)
+	}
+	parts := strings.Split(path, ".")
+	for _, p := range parts {
+		if p == "" {
+			return nil, "", fmt.Errorf("invalid %s path %q", flag, path)
+		}
+	}
+	return parts, value, nil
+}
+
+// typedLiteral interprets a --set value: JSON numbers, true, false and null
+// keep their type, anything else is a string. Use --set-json to force a
+// string that looks like a number, e.g. --set-json 'zip="02134"'.
+func typedLiteral(v string) any {
+	switch v {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if _, err := strconv.ParseFloat(v, 64); err == nil && json.Valid([]byte(v)) {
+		return json.Number(v)
+	}
+	return v
+}
+
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+// setPath deep-merges value into doc at path, creating objects along the
+// way. Objects are merged key by key; anything else replaces what was
+// there.
+func setPath(doc map[string]any, path []string, value any) error {
+	cur := doc
+	for i, key := range path[:len(path)-1] {
+		next, ok := cur[key].(map[string]any)
+		if !ok {
+			if cur[key] != nil {
+				return fmt.Errorf("cannot set %s: %s is not an object", strings.Join(path, "."), strings.Join(path[:i+1], "."))
+			}
+			next = make(map[string]any)
+			cur[key] = next
+		}
+		cur = next
+	}
+	key := path[len(path)-1]
+	cur[key] = mergeValue(cur[key], value)
+	return nil
+}
+
+func mergeValue(dst, src any) any {
+	d, dok := dst.(map[string]any)
+	s, sok := src.(map[string]any)
+	if !dok || !sok {
+		return src
+	}
+	for k, v := range s {
+		d[k] = mergeValue(d[k], v)
+	}
+	return d
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -22,7 +23,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/broadcasts", nil)
+			data, err := c.Get(cmd.Context(), "/v1/broadcasts", nil)
 			if err != nil {
 				return err
 			}
@@ -39,7 +40,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/broadcasts/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/broadcasts/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -47,7 +48,7 @@ func init() {
 		},
 	}
 
-	trigger := &cobra.Command{
+	trigger := destructive(&cobra.Command{
 		Use:   "trigger <id>",
 		Short: "Trigger a broadcast",
 		Args:  cobra.ExactArgs(1),
@@ -56,6 +57,12 @@ func init() {
 			if err != nil {
 				return err
 			}
+			err = confirm(cmd, c, func(ctx context.Context) (string, error) {
+				return describeResource(ctx, c, "Trigger", "broadcast", args[0], fmt.Sprintf("/v1/campaigns/%s", args[0]))
+			})
+			if err != nil {
+				return err
+			}
 			body, err := readBody(cmd)
 			if err != nil {
 				return err
@@ -63,13 +70,13 @@ func init() {
 			if body == nil {
 				body = json.RawMessage(`{}`)
 			}
-			data, err := c.Post(fmt.Sprintf("/v1/campaigns/%s/triggers", args[0]), body)
+			data, err := c.Post(cmd.Context(), fmt.Sprintf("/v1/campaigns/%s/triggers", args[0]), body)
 			if err != nil {
 				return err
 			}
 			return printJSON(data)
 		},
-	}
+	})
 	addBodyFlag(trigger)
 
 	triggers := &cobra.Command{
@@ -81,7 +88,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/broadcasts/%s/triggers", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/broadcasts/%s/triggers", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -98,7 +105,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/broadcasts/%s/triggers/%s", args[0], args[1]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/broadcasts/%s/triggers/%s", args[0], args[1]), nil)
 			if err != nil {
 				return err
 			}
@@ -115,7 +122,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/broadcasts/%s/triggers/%s/errors", args[0], args[1]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/broadcasts/%s/triggers/%s/errors", args[0], args[1]), nil)
 			if err != nil {
 				return err
 			}
@@ -132,7 +139,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/broadcasts/%s/actions", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/broadcasts/%s/actions", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -155,13 +162,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
@@ -179,7 +186,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/broadcasts/%s/metrics", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/broadcasts/%s/metrics", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -196,7 +203,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/broadcasts/%s/metrics/links", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/broadcasts/%s/metrics/links", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -213,7 +220,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/broadcasts/%s/actions/%s/metrics", args[0], args[1]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/broadcasts/%s/actions/%s/metrics", args[0], args[1]), nil)
 			if err != nil {
 				return err
 			}
@@ -230,7 +237,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/broadcasts/%s/actions/%s/metrics/links", args[0], args[1]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/broadcasts/%s/actions/%s/metrics/links", args[0], args[1]), nil)
 			if err != nil {
 				return err
 			}
@@ -247,13 +254,10 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/broadcasts/%s/messages", args[0]), nil)
-			if err != nil {
-				return err
-			}
-			return printJSON(data)
+			return listPages(cmd, c, fmt.Sprintf("/v1/broadcasts/%s/messages", args[0]), nil)
 		},
 	}
+	addPageFlags(bcastMessages)
 
 	bcastTranslation := &cobra.Command{
 		Use:   "translation <id> <action-id> <lang>",
@@ -270,13 +274,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
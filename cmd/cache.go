@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/leechael/cio/internal/client"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	parent := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and clear the response cache",
+		Long: "With cache=true in the profile (or CIO_CACHE=1), GET responses for\n" +
+			"segments, campaigns, index and subscription_topics are kept on disk, keyed\n" +
+			"by profile, region and URL, for 5m (segments, campaigns) or 1h (index,\n" +
+			"subscription_topics). Set cache_ttls.<resource> to change a TTL, cache\n" +
+			"another resource, or disable one with 0.\n\n" +
+			"A request that changes a resource drops its cached responses. --refresh\n" +
+			"fetches fresh data and updates the cache; --no-cache bypasses it.",
+	}
+
+	stats := &cobra.Command{
+		Use:   "stats",
+		Short: "Show cached responses per resource",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := client.DefaultCacheDir()
+			if err != nil {
+				return err
+			}
+			stats, err := client.CacheStats(dir)
+			if err != nil {
+				return err
+			}
+			return printObject(map[string]any{"dir": dir, "resources": stats})
+		},
+	}
+
+	clear := &cobra.Command{
+		Use:     "clear [resource...]",
+		Short:   "Remove cached responses",
+		Example: "  cio cache clear\n  cio cache clear segments campaigns",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := client.DefaultCacheDir()
+			if err != nil {
+				return err
+			}
+			n, err := client.ClearCache(dir, args...)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed %d cached responses\n", n)
+			return nil
+		},
+	}
+
+	parent.AddCommand(stats, clear)
+	rootCmd.AddCommand(parent)
+}
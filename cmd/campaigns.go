@@ -21,7 +21,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/campaigns", nil)
+			data, err := c.Get(cmd.Context(), "/v1/campaigns", nil)
 			if err != nil {
 				return err
 			}
@@ -38,7 +38,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/campaigns/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/campaigns/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -55,7 +55,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/campaigns/%s/actions", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/campaigns/%s/actions", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -78,13 +78,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
@@ -102,7 +102,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/campaigns/%s/metrics", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/campaigns/%s/metrics", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -119,7 +119,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/campaigns/%s/metrics/links", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/campaigns/%s/metrics/links", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -136,7 +136,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/campaigns/%s/actions/%s/metrics", args[0], args[1]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/campaigns/%s/actions/%s/metrics", args[0], args[1]), nil)
 			if err != nil {
 				return err
 			}
@@ -153,7 +153,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/campaigns/%s/actions/%s/metrics/links", args[0], args[1]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/campaigns/%s/actions/%s/metrics/links", args[0], args[1]), nil)
 			if err != nil {
 				return err
 			}
@@ -170,7 +170,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/campaigns/%s/journey_metrics", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/campaigns/%s/journey_metrics", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -187,13 +187,10 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/campaigns/%s/messages", args[0]), nil)
-			if err != nil {
-				return err
-			}
-			return printJSON(data)
+			return listPages(cmd, c, fmt.Sprintf("/v1/campaigns/%s/messages", args[0]), nil)
 		},
 	}
+	addPageFlags(messages)
 
 	translation := &cobra.Command{
 		Use:   "translation <id> <action-id> <lang>",
@@ -210,13 +207,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
@@ -2,13 +2,24 @@ package cmd
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/leechael/cio/internal/client"
+	"github.com/leechael/cio/internal/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -34,9 +45,52 @@ func setupTestServer(t *testing.T, handler http.HandlerFunc) func() {
 	}
 }
 
+func setupTrackServer(t *testing.T, handler http.HandlerFunc) func() {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+
+	orig := newTrackClient
+	newTrackClient = func() (*client.Client, error) {
+		return &client.Client{
+			BaseURL:    srv.URL,
+			Username:   "test-site",
+			Token:      "test-key",
+			HTTPClient: srv.Client(),
+		}, nil
+	}
+
+	return func() {
+		newTrackClient = orig
+		srv.Close()
+	}
+}
+
+func setupPipelinesServer(t *testing.T, handler http.HandlerFunc) func() {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+
+	orig := newPipelinesClient
+	newPipelinesClient = func() (*client.Client, error) {
+		return &client.Client{
+			BaseURL:    srv.URL,
+			Username:   "test-write-key",
+			HTTPClient: srv.Client(),
+		}, nil
+	}
+
+	return func() {
+		newPipelinesClient = orig
+		srv.Close()
+	}
+}
+
 func resetFlags(cmd *cobra.Command) {
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
-		_ = f.Value.Set(f.DefValue)
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			_ = v.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
 		f.Changed = false
 	})
 	for _, sub := range cmd.Commands() {
@@ -49,6 +103,17 @@ func executeCommand(args ...string) (string, error) {
 	region = "us"
 	jsonOutput = false
 	plainOutput = false
+	outputFormat, columns, sortBy, noHeaders = "", nil, "", false
+	records = nil
+	retries = client.DefaultMaxRetries
+	retryMaxWait = client.DefaultRetryMaxWait
+	timeout = client.DefaultTimeout
+	dryRun = ""
+	assumeYes = false
+	readOnly = false
+	verbose, traceHTTP, traceFile = false, false, ""
+	recordDir, replayDir, replayer = "", "", nil
+	noCache, refreshCache = false, false
 	resetFlags(rootCmd)
 
 	old := os.Stdout
@@ -144,7 +209,7 @@ func TestSegmentsDelete(t *testing.T) {
 	})
 	defer cleanup()
 
-	_, err := executeCommand("segments", "rm", "99")
+	_, err := executeCommand("segments", "rm", "99", "--yes")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -159,7 +224,7 @@ func TestSegmentsDeleteAlias(t *testing.T) {
 	})
 	defer cleanup()
 
-	_, err := executeCommand("segments", "delete", "1")
+	_, err := executeCommand("segments", "delete", "1", "--yes")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -334,6 +399,69 @@ func TestJQFilter(t *testing.T) {
 	}
 }
 
+func TestTableOutput(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"segments":[{"id":7,"name":"VIP","type":"manual"},{"id":1,"name":"Active","type":"dynamic"}]}`))
+	})
+	defer cleanup()
+
+	out, err := executeCommand("segments", "ls", "--output", "table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ID  NAME    TYPE     STATE\n7   VIP     manual\n1   Active  dynamic\n"
+	if out != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, want)
+	}
+
+	out, err = executeCommand("segments", "ls", "--output", "table", "--columns", "name,id", "--sort-by", "id", "--no-headers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "Active  1\nVIP     7\n" {
+		t.Fatalf("got:\n%s", out)
+	}
+
+	// Piped output stays JSON unless a table is asked for.
+	out, err = executeCommand("segments", "ls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(out), "{") {
+		t.Fatalf("got:\n%s", out)
+	}
+
+	old := stdoutIsTerminal
+	stdoutIsTerminal = func() bool { return true }
+	defer func() { stdoutIsTerminal = old }()
+	t.Setenv("COLUMNS", "80")
+	out, err = executeCommand("segments", "ls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "ID  NAME") {
+		t.Fatalf("terminal default: got:\n%s", out)
+	}
+	out, err = executeCommand("segments", "ls", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(out), "{") {
+		t.Fatalf("--json: got:\n%s", out)
+	}
+}
+
+func TestTableOutputErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"segments", "ls", "--output", "yaml"},
+		{"segments", "ls", "--output", "table", "--jq", ".segments"},
+	} {
+		if _, err := executeCommand(args...); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
 func TestJQRequiresJSONMode(t *testing.T) {
 	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write([]byte(`{"segments":[]}`))
@@ -365,6 +493,104 @@ func TestHTTPError(t *testing.T) {
 	}
 }
 
+func TestNegativeRetriesRejected(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+	defer cleanup()
+
+	_, err := executeCommand("segments", "ls", "--retries", "-1")
+	if err == nil || !strings.Contains(err.Error(), "--retries") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	cases := []struct {
+		status int
+		want   int
+	}{
+		{404, exitNotFound},
+		{401, exitAuth},
+		{403, exitAuth},
+		{429, exitRateLimited},
+		{400, exitValidation},
+		{422, exitValidation},
+		{500, exitServer},
+		{409, exitError},
+	}
+	for _, tc := range cases {
+		err := fmt.Errorf("wrapped: %w", &client.APIError{StatusCode: tc.status})
+		if got := exitCode(err); got != tc.want {
+			t.Errorf("%d: got %d, want %d", tc.status, got, tc.want)
+		}
+	}
+	if got := exitCode(errors.New("boom")); got != exitError {
+		t.Errorf("plain error: got %d", got)
+	}
+	if got := exitCode(fmt.Errorf("stopped: %w", context.Canceled)); got != exitInterrupted {
+		t.Errorf("canceled: got %d", got)
+	}
+}
+
+func TestWriteErrorJSON(t *testing.T) {
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+
+	var buf bytes.Buffer
+	writeError(&buf, &client.APIError{
+		StatusCode: 404,
+		Method:     "GET",
+		Path:       "/v1/segments/9",
+		RequestID:  "req-1",
+		Errors:     []client.ErrorDetail{{Detail: "not found"}},
+	})
+
+	var doc struct {
+		Error struct {
+			Message   string `json:"message"`
+			ExitCode  int    `json:"exit_code"`
+			Status    int    `json:"status"`
+			Path      string `json:"path"`
+			RequestID string `json:"request_id"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if doc.Error.Message != "not found" || doc.Error.ExitCode != exitNotFound || doc.Error.Status != 404 ||
+		doc.Error.Path != "/v1/segments/9" || doc.Error.RequestID != "req-1" {
+		t.Fatalf("got %+v", doc.Error)
+	}
+}
+
+func TestOutputJSONMatchesJSONFlag(t *testing.T) {
+	out, err := executeCommand("version", "--output", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]string
+	if err := json.Unmarshal([]byte(out), &v); err != nil || v["cli"] != "cio" {
+		t.Fatalf("version: %v: %q", err, out)
+	}
+
+	// executeCommand leaves the parsed flags in place.
+	defer func() { outputFormat = "" }()
+	var buf bytes.Buffer
+	writeError(&buf, errors.New("boom"))
+	if !strings.HasPrefix(buf.String(), `{`) {
+		t.Fatalf("error: got %q", buf.String())
+	}
+}
+
+func TestWriteErrorPlain(t *testing.T) {
+	var buf bytes.Buffer
+	writeError(&buf, errors.New("boom"))
+	if buf.String() != "Error: boom\n" {
+		t.Fatalf("got %q", buf.String())
+	}
+}
+
 func TestBroadcastsTrigger(t *testing.T) {
 	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -377,7 +603,7 @@ func TestBroadcastsTrigger(t *testing.T) {
 	})
 	defer cleanup()
 
-	_, err := executeCommand("broadcasts", "trigger", "7", "--body", `{}`)
+	_, err := executeCommand("broadcasts", "trigger", "7", "--body", `{}`, "--yes")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -484,6 +710,224 @@ func TestExportsDownload(t *testing.T) {
 	}
 }
 
+func TestExportsDownloadToFile(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, _ = zw.Write([]byte("id,email\n1,a@example.com\n"))
+	_ = zw.Close()
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/exports/55/download":
+			fmt.Fprintf(w, `{"url":"http://%s/files/55.csv.gz?sig=x"}`, r.Host)
+		case "/files/55.csv.gz":
+			if r.Header.Get("Authorization") != "" {
+				t.Error("API token sent to the signed URL")
+			}
+			_, _ = w.Write(gz.Bytes())
+		default:
+			t.Errorf("path = %s", r.URL.Path)
+		}
+	})
+	defer cleanup()
+
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "deliveries.csv")
+	if _, err := executeCommand("exports", "download", "55", "--file", csvPath); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(csvPath); string(got) != "id,email\n1,a@example.com\n" {
+		t.Fatalf("got %q", got)
+	}
+
+	jsonPath := filepath.Join(dir, "deliveries.ndjson")
+	if _, err := executeCommand("exports", "download", "55", "--file", jsonPath, "--format", "ndjson"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(jsonPath); string(got) != `{"id":"1","email":"a@example.com"}`+"\n" {
+		t.Fatalf("got %q", got)
+	}
+
+	if _, err := executeCommand("exports", "download", "55", "--format", "xml", "--file", jsonPath); err == nil {
+		t.Fatal("expected error for --format xml")
+	}
+}
+
+func TestExportsDownloadOutputIsFormat(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/exports/55/download" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"url":"https://example.com/55.csv.gz"}`))
+	})
+	defer cleanup()
+
+	out, err := executeCommand("exports", "download", "55", "--output", "table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "url  https://example.com/55.csv.gz\n" {
+		t.Fatalf("got %q", out)
+	}
+	if _, err := os.Stat("table"); !os.IsNotExist(err) {
+		t.Fatalf("--output was taken as a path: %v", err)
+	}
+}
+
+func shortExportPolls(t *testing.T) {
+	t.Helper()
+	interval, maxInterval := exportPollInterval, exportPollMax
+	exportPollInterval, exportPollMax = time.Millisecond, time.Millisecond
+	t.Cleanup(func() { exportPollInterval, exportPollMax = interval, maxInterval })
+}
+
+func TestExportsWait(t *testing.T) {
+	shortExportPolls(t)
+	polls := 0
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		status := "processing"
+		if polls == 3 {
+			status = "done"
+		}
+		fmt.Fprintf(w, `{"export":{"id":55,"status":%q}}`, status)
+	})
+	defer cleanup()
+
+	out, err := executeCommand("exports", "wait", "55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls != 3 || !strings.Contains(out, `"done"`) {
+		t.Fatalf("polls = %d, out = %s", polls, out)
+	}
+}
+
+func TestExportsWaitFailed(t *testing.T) {
+	shortExportPolls(t)
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"export":{"id":55,"status":"failed","failed":true,"error":"segment was deleted"}}`))
+	})
+	defer cleanup()
+
+	_, err := executeCommand("exports", "wait", "55")
+	if err == nil || err.Error() != "export 55 failed: segment was deleted" {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestExportsWaitTimeout(t *testing.T) {
+	shortExportPolls(t)
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"export":{"id":55,"status":"pending"}}`))
+	})
+	defer cleanup()
+
+	_, err := executeCommand("exports", "wait", "55", "--wait-timeout", "20ms")
+	if err == nil || !strings.Contains(err.Error(), "not done after 20ms (status pending)") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestExportsRun(t *testing.T) {
+	shortExportPolls(t)
+	var created map[string]any
+	polls := 0
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/exports/customers":
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			_, _ = w.Write([]byte(`{"export":{"id":56,"status":"pending"}}`))
+		case "/v1/exports/56":
+			polls++
+			status := "processing"
+			if polls > 1 {
+				status = "done"
+			}
+			fmt.Fprintf(w, `{"export":{"id":56,"status":%q}}`, status)
+		case "/v1/exports/56/download":
+			fmt.Fprintf(w, `{"url":"http://%s/files/56.csv"}`, r.Host)
+		case "/files/56.csv":
+			_, _ = w.Write([]byte("id,email\n1,a@example.com\n"))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	})
+	defer cleanup()
+
+	path := filepath.Join(t.TempDir(), "customers.ndjson")
+	_, err := executeCommand("exports", "run", "customers",
+		"--filter", `{"and":[{"segment":{"id":7}}]}`, "--file", path, "--format", "ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters, _ := json.Marshal(created["filters"])
+	if string(filters) != `{"and":[{"segment":{"id":7}}]}` {
+		t.Fatalf("filters = %s", filters)
+	}
+	if got, _ := os.ReadFile(path); string(got) != `{"id":"1","email":"a@example.com"}`+"\n" {
+		t.Fatalf("got %q", got)
+	}
+
+	if _, err := executeCommand("exports", "run", "deliveries", "--filter", "{}", "--file", path); err == nil {
+		t.Fatal("expected error for --filter on a deliveries export")
+	}
+	if _, err := executeCommand("exports", "run", "customers"); err == nil {
+		t.Fatal("expected error without --file")
+	}
+}
+
+func TestFilterCompile(t *testing.T) {
+	out, err := executeCommand("filter", "compile", "segment=3 and not unsubscribed=true", "--plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"and":[{"segment":{"id":3}},{"not":{"attribute":{"field":"unsubscribed","operator":"eq","value":"true"}}}]}`
+	if strings.TrimSpace(out) != want {
+		t.Fatalf("got %s", out)
+	}
+	if _, err := executeCommand("filter", "compile", "segment=x"); err == nil || !strings.HasPrefix(err.Error(), "column 9") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestWhereFlag(t *testing.T) {
+	var bodies []map[string]any
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		_, _ = w.Write([]byte(`{}`))
+	})
+	defer cleanup()
+
+	if _, err := executeCommand("customers", "search", "--where", "segment=3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand("objects", "search", "--set", "object_type_id=1", "--where", "name exists"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand("exports", "create-customers", "--where", "segment=3"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(bodies)
+	want := `[{"filter":{"and":[{"segment":{"id":3}}]}},` +
+		`{"filter":{"and":[{"attribute":{"field":"name","operator":"exists"}}]},"object_type_id":1},` +
+		`{"filters":{"and":[{"segment":{"id":3}}]}}]`
+	if string(got) != want {
+		t.Fatalf("got %s", got)
+	}
+
+	if _, err := executeCommand("customers", "search", "--where", "plan ="); err == nil || !strings.HasPrefix(err.Error(), "--where: column 7") {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := executeCommand("customers", "search", "--email", "a@example.com", "--where", "segment=3"); err == nil {
+		t.Fatal("expected error for --email with --where")
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("invalid filters reached the server: %d requests", len(bodies))
+	}
+}
+
 func TestNewsletterTranslationMerge(t *testing.T) {
 	t.Run("GET", func(t *testing.T) {
 		cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
@@ -504,66 +948,1412 @@ func TestNewsletterTranslationMerge(t *testing.T) {
 	})
 }
 
-func TestTransactionalDeliveries(t *testing.T) {
+func TestNewsletterTestGroups(t *testing.T) {
+	groups := `{"test_groups":[
+		{"id":"a1b2c3","name":"Subject A","weight":50,"created":1700000000,"updated":1700000100,"content_ids":[11]},
+		{"id":"d4e5f6","name":"Subject B","weight":50,"created":1700000000,"updated":1700000100,"content_ids":[12,13]}
+	]}`
 	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/v1/transactional/12/deliveries" {
+		switch r.URL.Path {
+		case "/v1/newsletters/8/test_groups":
+			_, _ = w.Write([]byte(groups))
+		case "/v1/newsletters/8/contents/11/metrics", "/v1/newsletters/8/contents/12/metrics", "/v1/newsletters/8/contents/13/metrics":
+			id := strings.Split(r.URL.Path, "/")[5]
+			fmt.Fprintf(w, `{"metric":{"series":{"sent":[%s]}}}`, id)
+		default:
 			t.Errorf("path = %s", r.URL.Path)
 		}
-		_, _ = w.Write([]byte(`{"deliveries":[]}`))
 	})
 	defer cleanup()
 
-	_, err := executeCommand("transactional", "deliveries", "12")
+	out, err := executeCommand("newsletters", "test-groups", "8", "--metrics", "--jq", "[.test_groups[] | {name, metrics}]")
 	if err != nil {
 		t.Fatal(err)
 	}
+	var got []struct {
+		Name    string
+		Metrics map[string]struct {
+			Metric struct {
+				Series struct{ Sent []int }
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if len(got) != 2 || got[0].Name != "Subject A" || len(got[0].Metrics) != 1 || got[0].Metrics["11"].Metric.Series.Sent[0] != 11 ||
+		len(got[1].Metrics) != 2 || got[1].Metrics["13"].Metric.Series.Sent[0] != 13 {
+		t.Fatalf("got %s", out)
+	}
+
+	for _, bad := range []string{`{}`, `{"test_groups":{"id":"a"}}`, `{"test_groups":[{"id":"a","contents":[{"id":11}]}]}`} {
+		groups = bad
+		if _, err := executeCommand("newsletters", "test-groups", "8", "--metrics"); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
 }
 
-func TestInfoIPAddresses(t *testing.T) {
+func TestNewsletterTestGroupTranslation(t *testing.T) {
+	var methods []string
 	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/v1/info/ip_addresses" {
+		if r.URL.Path != "/v1/newsletters/8/test_groups/a/language/fr" {
 			t.Errorf("path = %s", r.URL.Path)
 		}
-		_, _ = w.Write([]byte(`{"addresses":["1.2.3.4"]}`))
+		methods = append(methods, r.Method)
+		_, _ = w.Write([]byte(`{}`))
 	})
 	defer cleanup()
 
-	out, err := executeCommand("info", "ip-addresses")
-	if err != nil {
+	if _, err := executeCommand("newsletters", "test-group-translation", "8", "a", "fr"); err != nil {
 		t.Fatal(err)
 	}
-	if !strings.Contains(out, "1.2.3.4") {
-		t.Fatalf("got %s", out)
+	if _, err := executeCommand("newsletters", "test-group-translation", "8", "a", "fr", "--body", `{"subject":"Bonjour"}`); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(methods, ",") != "GET,PUT" {
+		t.Fatalf("methods = %v", methods)
 	}
 }
 
-func TestIndexAttributes(t *testing.T) {
+func TestTransactionalDeliveries(t *testing.T) {
 	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/v1/index/attributes" {
+		if r.URL.Path != "/v1/transactional/12/deliveries" {
 			t.Errorf("path = %s", r.URL.Path)
 		}
-		_, _ = w.Write([]byte(`{"attributes":[]}`))
+		_, _ = w.Write([]byte(`{"deliveries":[]}`))
 	})
 	defer cleanup()
 
-	_, err := executeCommand("index", "attributes")
+	_, err := executeCommand("transactional", "deliveries", "12")
 	if err != nil {
 		t.Fatal(err)
 	}
 }
 
-func TestRequireBodyMissing(t *testing.T) {
-	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
-		_, _ = w.Write([]byte(`{}`))
-	})
+func pagedMessages(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		switch r.URL.Query().Get("start") {
+		case "":
+			_, _ = w.Write([]byte(`{"messages":[{"id":"m1"},{"id":"m2"}],"next":"c2"}`))
+		case "c2":
+			_, _ = w.Write([]byte(`{"messages":[{"id":"m3"},{"id":"m4"}],"next":"c3"}`))
+		case "c3":
+			_, _ = w.Write([]byte(`{"messages":[{"id":"m5"}],"next":""}`))
+		}
+	}
+}
+
+func TestMessagesListFirstPageOnly(t *testing.T) {
+	cleanup := setupTestServer(t, pagedMessages(t))
 	defer cleanup()
 
-	_, err := executeCommand("segments", "create")
-	if err == nil {
-		t.Fatal("expected error for missing body")
+	out, err := executeCommand("messages", "ls", "--jq", "[.messages[].id] | join(\",\")")
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !strings.Contains(err.Error(), "body is required") {
-		t.Fatalf("got %q", err.Error())
+	if strings.TrimSpace(out) != "m1,m2" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestMessagesListAll(t *testing.T) {
+	cleanup := setupTestServer(t, pagedMessages(t))
+	defer cleanup()
+
+	out, err := executeCommand("messages", "ls", "--all", "--jq", "[.messages[].id] | join(\",\")")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != "m1,m2,m3,m4,m5" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestMessagesListLimit(t *testing.T) {
+	cleanup := setupTestServer(t, pagedMessages(t))
+	defer cleanup()
+
+	out, err := executeCommand("messages", "ls", "--limit", "3", "--jq", "[.messages[].id] | join(\",\")")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != "m1,m2,m3" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestMessagesListLimitOnPageBoundaryKeepsCursor(t *testing.T) {
+	cleanup := setupTestServer(t, pagedMessages(t))
+	defer cleanup()
+
+	out, err := executeCommand("messages", "ls", "--limit", "4", "--jq", ".next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != "c3" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestMessagesListStartAndPageSize(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") != "abc" {
+			t.Errorf("start = %s", r.URL.Query().Get("start"))
+		}
+		if r.URL.Query().Get("limit") != "50" {
+			t.Errorf("limit = %s", r.URL.Query().Get("limit"))
+		}
+		_, _ = w.Write([]byte(`{"messages":[]}`))
+	})
+	defer cleanup()
+
+	if _, err := executeCommand("messages", "ls", "--start", "abc", "--page-size", "50"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMessagesListStream(t *testing.T) {
+	cleanup := setupTestServer(t, pagedMessages(t))
+	defer cleanup()
+
+	out, err := executeCommand("messages", "ls", "--all", "--stream", "--plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines: %q", len(lines), out)
+	}
+}
+
+func TestMessagesListRecords(t *testing.T) {
+	cleanup := setupTestServer(t, pagedMessages(t))
+	defer cleanup()
+
+	out, err := executeCommand("messages", "ls", "--all", "--output", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "id\nm1\nm2\nm3\nm4\nm5\n" {
+		t.Fatalf("csv: got %q", out)
+	}
+
+	out, err = executeCommand("messages", "ls", "--limit", "3", "--output", "ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != `{"id":"m1"}`+"\n"+`{"id":"m2"}`+"\n"+`{"id":"m3"}`+"\n" {
+		t.Fatalf("ndjson: got %q", out)
+	}
+
+	out, err = executeCommand("messages", "ls", "--all", "--output", "tsv", "--sort-by", "-id", "--no-headers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "m5\nm4\nm3\nm2\nm1\n" {
+		t.Fatalf("sorted tsv: got %q", out)
+	}
+	if _, err := executeCommand("messages", "ls", "--all", "--stream", "--output", "csv", "--sort-by", "id"); err == nil {
+		t.Fatal("expected --sort-by with --stream to fail")
+	}
+
+	if _, err := executeCommand("messages", "ls", "--output", "tsv", "--jq", ".messages"); err == nil {
+		t.Fatal("expected --jq with --output tsv to fail")
+	}
+}
+
+func TestMessagesListAllPartialOnError(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") == "" {
+			_, _ = w.Write([]byte(`{"messages":[{"id":"m1"}],"next":"c2"}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	defer cleanup()
+
+	out, err := executeCommand("messages", "ls", "--all", "--jq", ".next")
+	if err == nil || !strings.Contains(err.Error(), "partial") {
+		t.Fatalf("got %v", err)
+	}
+	if exitCode(err) != exitServer {
+		t.Fatalf("exit code = %d", exitCode(err))
+	}
+	if strings.TrimSpace(out) != "c2" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestSegmentsMembersAllParallelArrays(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") == "" {
+			_, _ = w.Write([]byte(`{"identifiers":[{"id":"a"}],"ids":["a"],"next":"n2"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"identifiers":[{"id":"b"}],"ids":["b"],"next":""}`))
+	})
+	defer cleanup()
+
+	out, err := executeCommand("segments", "members", "3", "--all", "--jq", ".ids | join(\",\")")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != "a,b" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestInfoIPAddresses(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/info/ip_addresses" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"addresses":["1.2.3.4"]}`))
+	})
+	defer cleanup()
+
+	out, err := executeCommand("info", "ip-addresses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "1.2.3.4") {
+		t.Fatalf("got %s", out)
+	}
+}
+
+func TestIndexAttributes(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/index/attributes" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"attributes":[]}`))
+	})
+	defer cleanup()
+
+	_, err := executeCommand("index", "attributes")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequireBodyMissing(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+	defer cleanup()
+
+	_, err := executeCommand("segments", "create")
+	if err == nil {
+		t.Fatal("expected error for missing body")
+	}
+	if !strings.Contains(err.Error(), "body is required") {
+		t.Fatalf("got %q", err.Error())
+	}
+}
+
+func TestConfigProfiles(t *testing.T) {
+	t.Setenv("CIO_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	t.Setenv("CIO_PROFILE", "")
+
+	if _, err := executeCommand("config", "set", "--profile", "staging", "region", "eu"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand("config", "set", "--profile", "staging", "token_env", "CIO_STAGING_TOKEN"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand("config", "use", "staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := executeCommand("config", "current")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != "staging" {
+		t.Fatalf("got %q", out)
+	}
+
+	out, err = executeCommand("config", "get", "region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != "eu" {
+		t.Fatalf("got %q", out)
+	}
+
+	t.Setenv("CIO_STAGING_TOKEN", "staging-token")
+	c, err := newClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.BaseURL != "https://api-eu.customer.io" || c.Token != "staging-token" {
+		t.Fatalf("got %s %s", c.BaseURL, c.Token)
+	}
+}
+
+func TestConfigUseUnknownProfile(t *testing.T) {
+	t.Setenv("CIO_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+
+	_, err := executeCommand("config", "use", "nope")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestConfigSetRejectsBadRateLimit(t *testing.T) {
+	t.Setenv("CIO_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+
+	_, err := executeCommand("config", "set", "rate_limits.general", "fast")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestTokenFileOverridesEnv(t *testing.T) {
+	t.Setenv("CIO_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	t.Setenv("CIO_PROFILE", "")
+	t.Setenv("CUSTOMERIO_API_TOKEN", "env-token")
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CUSTOMERIO_API_TOKEN_FILE", path)
+
+	c, err := newClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Token != "file-token" || !strings.Contains(c.TokenSource, path) {
+		t.Fatalf("got %q from %q", c.Token, c.TokenSource)
+	}
+}
+
+func TestReadOnlySources(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CIO_CONFIG", filepath.Join(dir, "config.json"))
+	t.Setenv("CIO_PROFILE", "")
+	t.Setenv("CUSTOMERIO_API_TOKEN", "tok")
+	t.Setenv("CIO_READ_ONLY", "")
+	defer func() { readOnly = false }()
+
+	check := func(want bool) {
+		t.Helper()
+		c, err := newClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.ReadOnly != want {
+			t.Fatalf("ReadOnly = %v, want %v", c.ReadOnly, want)
+		}
+	}
+	check(false)
+	readOnly = true
+	check(true)
+	readOnly = false
+	t.Setenv("CIO_READ_ONLY", "1")
+	check(true)
+	t.Setenv("CIO_READ_ONLY", "")
+
+	cfg, _ := config.Load()
+	_ = cfg.Profile("default").Set("read_only", "true")
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	check(true)
+	p, _ := activeProfile()
+	if on, source := readOnlyMode(p); !on || source != "profile default" {
+		t.Fatalf("got %v %q", on, source)
+	}
+}
+
+func TestConfigSetReadOnlyIsSticky(t *testing.T) {
+	t.Setenv("CIO_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	t.Setenv("CIO_PROFILE", "")
+	t.Setenv("CIO_READ_ONLY", "")
+
+	if _, err := executeCommand("config", "set", "read_only", "true"); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"config", "set", "read_only", "false"},
+		{"config", "set", "read_only", ""},
+		{"config", "set", "read_only", "false", "--read-only", "--yes"},
+		{"config", "set", "read_only", "false", "--yes"},
+	} {
+		if _, err := executeCommand(args...); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+	t.Setenv("CIO_READ_ONLY", "1")
+	if _, err := executeCommand("config", "set", "read_only", "false", "--yes"); err == nil || !strings.Contains(err.Error(), "CIO_READ_ONLY") {
+		t.Fatalf("env: got %v", err)
+	}
+	t.Setenv("CIO_READ_ONLY", "")
+
+	_, err := executeCommand("config", "set", "read_only", "false", "--yes")
+	if err == nil || !strings.Contains(err.Error(), "profile default") || !strings.Contains(err.Error(), "config.json") {
+		t.Fatalf("profile: got %v", err)
+	}
+
+	// config use only moves to another read-only profile.
+	for _, args := range [][]string{
+		{"config", "set", "--profile", "admin", "region", "us"},
+		{"config", "set", "--profile", "support", "read_only", "true"},
+		{"config", "use", "support"},
+	} {
+		if _, err := executeCommand(args...); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+	if _, err := executeCommand("config", "use", "admin"); err == nil || !strings.Contains(err.Error(), "not read-only") {
+		t.Fatalf("use: got %v", err)
+	}
+	cfg, _ := config.Load()
+	if !cfg.Profile("default").ReadOnly || cfg.CurrentProfile != "support" {
+		t.Fatalf("read_only = %v, current = %q", cfg.Profile("default").ReadOnly, cfg.CurrentProfile)
+	}
+}
+
+func TestReadOnlyCommands(t *testing.T) {
+	t.Setenv("CIO_AUDIT_LOG", "off")
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.Method+" "+r.URL.Path)
+		_, _ = w.Write([]byte(`{"identifiers":[]}`))
+	}))
+	defer srv.Close()
+	orig := newClient
+	defer func() { newClient = orig }()
+	newClient = func() (*client.Client, error) {
+		c := &client.Client{BaseURL: srv.URL, Token: "test-token", HTTPClient: srv.Client()}
+		return c, configureClient(c, &config.Profile{ReadOnly: true})
+	}
+
+	_, err := executeCommand("segments", "rm", "3")
+	var roErr *client.ReadOnlyError
+	if !errors.As(err, &roErr) {
+		t.Fatalf("got %v, want ReadOnlyError", err)
+	}
+	if _, err := executeCommand("customers", "search", "--body", `{"filter":{}}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0] != "POST /v1/customers" {
+		t.Fatalf("sent %v", sent)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	t.Setenv("CIO_AUDIT_LOG", filepath.Join(t.TempDir(), "audit.jsonl"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	orig := newClient
+	defer func() { newClient = orig }()
+	newClient = func() (*client.Client, error) {
+		c := &client.Client{BaseURL: srv.URL, Token: "test-token", HTTPClient: srv.Client()}
+		return c, configureClient(c, &config.Profile{Name: "prod", Region: "eu"})
+	}
+
+	if _, err := executeCommand("snippets", "ls"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand("snippets", "upsert", "--body", `{"name":"footer","value":"hi"}`); err != nil {
+		t.Fatal(err)
+	}
+	out, err := executeCommand("audit", "show", "--since", "1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []map[string]any
+	if err := json.Unmarshal([]byte(out), &entries); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries: %s", len(entries), out)
+	}
+	e := entries[0]
+	if e["method"] != "PUT" || e["path"] != "/v1/snippets" || e["status"] != float64(200) ||
+		e["profile"] != "prod" || e["region"] != "eu" || len(e["body_sha256"].(string)) != 64 {
+		t.Fatalf("unexpected entry: %v", e)
+	}
+
+	out, err = executeCommand("audit", "show", "--since", "2099-01-01")
+	if err != nil || strings.TrimSpace(out) != "[]" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	got := strings.Join(redactArgs([]string{"cio", "send", "email", "--body", `{"to":"a@b"}`, "--set=to=x", "-H", "X-Key: s", "--yes"}), " ")
+	want := "cio send email --body <redacted> --set=<redacted> -H <redacted> --yes"
+	if got != want {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestTraceFile(t *testing.T) {
+	t.Setenv("CIO_AUDIT_LOG", "off")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"segments":[]}`))
+	}))
+	defer srv.Close()
+	orig := newClient
+	defer func() { newClient = orig }()
+	var traced *client.Client
+	newClient = func() (*client.Client, error) {
+		c := &client.Client{BaseURL: srv.URL, Token: "test-token", HTTPClient: srv.Client()}
+		traced = c
+		return c, configureClient(c, &config.Profile{})
+	}
+	defer func() { traceSpans = nil }()
+
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	if _, err := executeCommand("segments", "ls", "--trace-file", path); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := traced.HTTPClient.Transport.(*client.Tracer); !ok {
+		t.Fatalf("transport = %T, want *client.Tracer", traced.HTTPClient.Transport)
+	}
+	if traceSpans == nil {
+		t.Fatal("span file not opened")
+	}
+	if err := traceSpans.Close("cio segments ls", nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 || !strings.Contains(string(data), `"name":"GET /v1/segments"`) {
+		t.Fatalf("unexpected spans:\n%s", data)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	t.Setenv("CIO_AUDIT_LOG", "off")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"segments":[{"id":3,"name":"Recorded"}]}`))
+	}))
+	orig := newClient
+	defer func() { newClient = orig }()
+	newClient = func() (*client.Client, error) {
+		c := &client.Client{BaseURL: srv.URL, Token: "secret-token", HTTPClient: srv.Client()}
+		return c, configureClient(c, &config.Profile{})
+	}
+
+	dir := t.TempDir()
+	if _, err := executeCommand("segments", "ls", "--record", dir); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("recorded %d files, want 1", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if strings.Contains(string(data), "secret-token") {
+		t.Fatalf("token leaked into cassette:\n%s", data)
+	}
+
+	out, err := executeCommand("segments", "ls", "--replay", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Recorded") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	if _, err := executeCommand("segments", "get", "3", "--replay", dir); !errors.Is(err, client.ErrNotRecorded) {
+		t.Fatalf("err = %v, want ErrNotRecorded", err)
+	}
+	if _, err := executeCommand("segments", "ls", "--replay", dir, "--record", dir); err == nil {
+		t.Fatal("expected error for --record with --replay")
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	t.Setenv("CIO_AUDIT_LOG", "off")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("CIO_CACHE", "1")
+	gets := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets++
+		}
+		fmt.Fprintf(w, `{"segments":[{"id":%d}]}`, gets)
+	}))
+	defer srv.Close()
+	orig := newClient
+	defer func() { newClient = orig }()
+	newClient = func() (*client.Client, error) {
+		c := &client.Client{BaseURL: srv.URL, Token: "test-token", HTTPClient: srv.Client()}
+		return c, configureClient(c, &config.Profile{Name: "default", Region: "us"})
+	}
+
+	steps := []struct {
+		args []string
+		gets int
+	}{
+		{[]string{"segments", "ls"}, 1},
+		{[]string{"segments", "ls"}, 1},
+		{[]string{"segments", "ls", "--no-cache"}, 2},
+		{[]string{"segments", "ls", "--refresh"}, 3},
+		{[]string{"segments", "ls"}, 3},
+		{[]string{"segments", "rm", "7", "--yes"}, 3},
+		{[]string{"segments", "ls"}, 4},
+	}
+	for _, step := range steps {
+		if _, err := executeCommand(step.args...); err != nil {
+			t.Fatalf("%v: %v", step.args, err)
+		}
+		if gets != step.gets {
+			t.Fatalf("after %v: %d GETs, want %d", step.args, gets, step.gets)
+		}
+	}
+
+	out, err := executeCommand("cache", "stats", "--jq", ".resources[0].entries")
+	if err != nil || strings.TrimSpace(out) != "1" {
+		t.Fatalf("stats: %q, %v", out, err)
+	}
+	out, err = executeCommand("cache", "clear")
+	if err != nil || !strings.Contains(out, "Removed 1 cached responses") {
+		t.Fatalf("clear: %q, %v", out, err)
+	}
+}
+
+func TestTrackIdentify(t *testing.T) {
+	var got map[string]any
+	cleanup := setupTrackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/entity" || r.Method != http.MethodPost {
+			t.Errorf("%s %s", r.Method, r.URL.Path)
+		}
+		if user, _, _ := r.BasicAuth(); user != "test-site" {
+			t.Errorf("user = %q", user)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	})
+	defer cleanup()
+
+	_, err := executeCommand("track", "identify", "u@example.com", "--by", "email", "--body", `{"plan":"pro"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"action":"identify","attributes":{"plan":"pro"},"identifiers":{"email":"u@example.com"},"type":"person"}`
+	if data, _ := json.Marshal(got); string(data) != want {
+		t.Fatalf("got %s", data)
+	}
+}
+
+func TestTrackObjectDelete(t *testing.T) {
+	var got map[string]any
+	cleanup := setupTrackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	})
+	defer cleanup()
+
+	if _, err := executeCommand("track", "delete", "acme", "--object-type", "1"); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"action":"delete","identifiers":{"object_id":"acme","object_type_id":"1"},"type":"object"}`
+	if data, _ := json.Marshal(got); string(data) != want {
+		t.Fatalf("got %s", data)
+	}
+}
+
+func TestTrackEvent(t *testing.T) {
+	var got map[string]any
+	cleanup := setupTrackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	})
+	defer cleanup()
+
+	if _, err := executeCommand("track", "event", "42", "purchase", "--timestamp", "1700000000", "--body", `{"total":9.5}`); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"action":"event","attributes":{"total":9.5},"identifiers":{"id":"42"},"name":"purchase","timestamp":1700000000,"type":"person"}`
+	if data, _ := json.Marshal(got); string(data) != want {
+		t.Fatalf("got %s", data)
+	}
+}
+
+func TestTrackRejectsBadInput(t *testing.T) {
+	cleanup := setupTrackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+	defer cleanup()
+
+	if _, err := executeCommand("track", "suppress", "42", "--by", "phone"); err == nil {
+		t.Fatal("expected error for --by phone")
+	}
+	if _, err := executeCommand("track", "identify", "42", "--body", `[1]`); err == nil {
+		t.Fatal("expected error for non-object attributes")
+	}
+}
+
+func readReport(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestTrackBatchNDJSON(t *testing.T) {
+	var batches [][]map[string]any
+	cleanup := setupTrackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/batch" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var body struct {
+			Batch []map[string]any `json:"batch"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		batches = append(batches, body.Batch)
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`{"errors":[{"batch_index":1,"reason":"invalid","message":"bad action"}]}`))
+	})
+	defer cleanup()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "ops.ndjson")
+	ndjson := `{"type":"person","action":"identify","identifiers":{"id":"1"}}
+
+not json
+{"type":"person","action":"bogus","identifiers":{"id":"2"}}
+{"type":"person","action":"identify","identifiers":{"id":"3"}}
+`
+	if err := os.WriteFile(input, []byte(ndjson), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := executeCommand("track", "batch", "--file", input)
+	if err == nil || !strings.Contains(err.Error(), "2 operation(s) failed") {
+		t.Fatalf("got %v", err)
+	}
+	if len(batches) != 1 || len(batches[0]) != 3 {
+		t.Fatalf("batches = %v", batches)
+	}
+
+	report := readReport(t, input+".report.ndjson")
+	want := map[float64]string{1: "ok", 3: "failed", 4: "failed", 5: "ok"}
+	if len(report) != len(want) {
+		t.Fatalf("report = %v", report)
+	}
+	for _, r := range report {
+		if want[r["line"].(float64)] != r["status"] {
+			t.Fatalf("report = %v", report)
+		}
+	}
+	if _, err := os.Stat(input + ".checkpoint"); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint left behind: %v", err)
+	}
+}
+
+func TestTrackBatchCSV(t *testing.T) {
+	var got []map[string]any
+	cleanup := setupTrackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Batch []map[string]any `json:"batch"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		got = body.Batch
+	})
+	defer cleanup()
+
+	input := filepath.Join(t.TempDir(), "people.csv")
+	csv := "email,plan,internal\nu@example.com,pro,x\n,free,y\n"
+	if err := os.WriteFile(input, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := executeCommand("track", "batch", "--file", input, "--map", "email=identifiers.email", "--map", "internal=-")
+	if err == nil || !strings.Contains(err.Error(), "1 operation(s) failed") {
+		t.Fatalf("got %v", err)
+	}
+	want := `[{"action":"identify","attributes":{"plan":"pro"},"identifiers":{"email":"u@example.com"},"type":"person"}]`
+	if data, _ := json.Marshal(got); string(data) != want {
+		t.Fatalf("got %s", data)
+	}
+	report := readReport(t, input+".report.ndjson")
+	if len(report) != 2 || report[1]["error"] != "row has no identifiers" {
+		t.Fatalf("report = %v", report)
+	}
+}
+
+// batchIDs collects the identifiers a fake Track server receives from
+// concurrent batch workers.
+type batchIDs struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (b *batchIDs) add(r *http.Request) {
+	var body struct {
+		Batch []struct {
+			Identifiers map[string]string `json:"identifiers"`
+		} `json:"batch"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, op := range body.Batch {
+		b.ids = append(b.ids, op.Identifiers["id"])
+	}
+}
+
+func (b *batchIDs) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Join(b.ids, ",")
+}
+
+func TestTrackBatchResume(t *testing.T) {
+	var ids batchIDs
+	var fail atomic.Bool
+	fail.Store(true)
+	cleanup := setupTrackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			// Drop the connection so the outcome is unknown.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		ids.add(r)
+	})
+	defer cleanup()
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "ops.ndjson")
+	var lines []string
+	for i := 1; i <= 4; i++ {
+		lines = append(lines, fmt.Sprintf(`{"type":"person","action":"identify","identifiers":{"id":"%d"}}`, i))
+	}
+	if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := executeCommand("track", "batch", "--file", input)
+	if err == nil || !strings.Contains(err.Error(), "--resume") {
+		t.Fatalf("got %v", err)
+	}
+
+	// Pretend lines 1-2 and 4 made it before the interruption.
+	cp := filepath.Join(dir, "ops.ndjson.checkpoint")
+	if err := os.WriteFile(cp, []byte(`{"input":"`+input+`","line":2,"done":[[4,4]]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fail.Store(false)
+	if _, err := executeCommand("track", "batch", "--file", input, "--resume"); err != nil {
+		t.Fatal(err)
+	}
+	if ids.String() != "3" {
+		t.Fatalf("sent %s", ids.String())
+	}
+	if _, err := os.Stat(cp); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint left behind: %v", err)
+	}
+}
+
+func TestTrackBatchResumeAfterServerError(t *testing.T) {
+	var ids batchIDs
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	cleanup := setupTrackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if code := int(status.Load()); code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		ids.add(r)
+	})
+	defer cleanup()
+
+	input := filepath.Join(t.TempDir(), "ops.ndjson")
+	ndjson := `{"type":"person","action":"identify","identifiers":{"id":"1"}}
+{"type":"person","action":"identify","identifiers":{"id":"2"}}
+`
+	if err := os.WriteFile(input, []byte(ndjson), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := executeCommand("track", "batch", "--file", input, "--retries", "0")
+	if err == nil || !strings.Contains(err.Error(), "--resume") {
+		t.Fatalf("got %v", err)
+	}
+	if data, _ := os.ReadFile(input + ".report.ndjson"); len(data) != 0 {
+		t.Fatalf("ops with an unknown outcome were reported: %s", data)
+	}
+
+	status.Store(http.StatusOK)
+	if _, err := executeCommand("track", "batch", "--file", input, "--resume"); err != nil {
+		t.Fatal(err)
+	}
+	if ids.String() != "1,2" {
+		t.Fatalf("sent %s", ids.String())
+	}
+}
+
+func TestTrackBatchRejectedChunk(t *testing.T) {
+	cleanup := setupTrackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"errors":[{"detail":"malformed batch"}]}`))
+	})
+	defer cleanup()
+
+	input := filepath.Join(t.TempDir(), "ops.ndjson")
+	ndjson := `{"type":"person","action":"identify","identifiers":{"id":"1"}}
+{"type":"person","action":"identify","identifiers":{"id":"2"}}
+`
+	if err := os.WriteFile(input, []byte(ndjson), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := executeCommand("track", "batch", "--file", input)
+	if err == nil || !strings.Contains(err.Error(), "2 operation(s) failed") {
+		t.Fatalf("got %v", err)
+	}
+	report := readReport(t, input+".report.ndjson")
+	if len(report) != 2 || report[0]["status"] != "failed" || !strings.Contains(report[1]["error"].(string), "malformed batch") {
+		t.Fatalf("report = %v", report)
+	}
+	if _, err := os.Stat(input + ".checkpoint"); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint left behind: %v", err)
+	}
+}
+
+func TestTrackBatchDryRun(t *testing.T) {
+	cleanup := setupTrackServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("dry run sent %s %s", r.Method, r.URL.Path)
+	})
+	defer cleanup()
+	var out bytes.Buffer
+	orig := newTrackClient
+	defer func() { newTrackClient = orig }()
+	newTrackClient = func() (*client.Client, error) {
+		c, err := orig()
+		if err != nil {
+			return nil, err
+		}
+		if err := configureClient(c, &config.Profile{}); err != nil {
+			return nil, err
+		}
+		c.DryRun.Out = &out
+		return c, nil
+	}
+
+	// Operations just under the size limit, so they fill three requests.
+	input := filepath.Join(t.TempDir(), "ops.ndjson")
+	padding := strings.Repeat("x", client.MaxOperationBytes-100)
+	var ndjson strings.Builder
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&ndjson, `{"type":"person","action":"identify","identifiers":{"id":"%d"},"attributes":{"note":"%s"}}`+"\n", i, padding)
+	}
+	if err := os.WriteFile(input, []byte(ndjson.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := executeCommand("track", "batch", "--file", input, "--dry-run=curl")
+	if !errors.Is(err, client.ErrDryRun) {
+		t.Fatalf("got %v, want ErrDryRun", err)
+	}
+	if n := strings.Count(out.String(), "curl -X POST"); n != 3 {
+		t.Fatalf("printed %d requests, want 3", n)
+	}
+	for _, path := range []string{input + ".checkpoint", input + ".report.ndjson"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("%s written: %v", path, err)
+		}
+	}
+}
+
+func TestPipelinesIdentify(t *testing.T) {
+	var got []byte
+	cleanup := setupPipelinesServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/identify" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if user, _, _ := r.BasicAuth(); user != "test-write-key" {
+			t.Errorf("user = %q", user)
+		}
+		got, _ = io.ReadAll(r.Body)
+	})
+	defer cleanup()
+
+	_, err := executeCommand("pipelines", "identify", "--body", `{"userId":"old","traits":{"id":12345678901234567}}`,
+		"--user-id", "42", "--trait", "plan=pro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"traits":{"id":12345678901234567,"plan":"pro"},"userId":"42"}`
+	if string(got) != want {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestPipelinesTrackRequiresEvent(t *testing.T) {
+	cleanup := setupPipelinesServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+	defer cleanup()
+
+	_, err := executeCommand("pipelines", "track", "--user-id", "42")
+	if err == nil || !strings.Contains(err.Error(), "--event") {
+		t.Fatalf("got %v", err)
+	}
+	_, err = executeCommand("pipelines", "page", "--name", "Home")
+	if err == nil || !strings.Contains(err.Error(), "--user-id or --anonymous-id") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestPipelinesBatchWrapsArray(t *testing.T) {
+	var got map[string]any
+	cleanup := setupPipelinesServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/batch" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	})
+	defer cleanup()
+
+	_, err := executeCommand("pipelines", "batch", "--body", `[{"type":"track","userId":"1","event":"x"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch, ok := got["batch"].([]any); !ok || len(batch) != 1 {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestAPIGetWithFields(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/messages" {
+			t.Errorf("%s %s", r.Method, r.URL.Path)
+		}
+		if q := r.URL.Query(); q.Get("type") != "email" || q.Get("limit") != "5" || q.Get("metric") != "sent" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		if r.Header.Get("X-Debug") != "1" {
+			t.Errorf("header = %v", r.Header)
+		}
+		_, _ = w.Write([]byte(`{"messages":[{"id":"m1"}]}`))
+	})
+	defer cleanup()
+
+	out, err := executeCommand("api", "v1/messages?metric=sent", "-X", "get", "-f", "type=email", "-F", "limit=5", "-H", "X-Debug: 1", "--jq", ".messages[0].id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != "m1" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestAPIPostFields(t *testing.T) {
+	var got []byte
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/segments" {
+			t.Errorf("%s %s", r.Method, r.URL.Path)
+		}
+		got, _ = io.ReadAll(r.Body)
+	})
+	defer cleanup()
+
+	_, err := executeCommand("api", "/v1/segments", "-f", "segment[name]=VIP", "-F", "segment[tags][]=1", "-F", "segment[tags][]=true", "-F", "active=null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"active":null,"segment":{"name":"VIP","tags":[1,true]}}`
+	if string(got) != want {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestAPIFieldFromFile(t *testing.T) {
+	var got map[string]string
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s", r.Method)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	})
+	defer cleanup()
+
+	path := filepath.Join(t.TempDir(), "footer.html")
+	if err := os.WriteFile(path, []byte("<p>bye</p>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand("api", "-X", "PUT", "/v1/snippets", "-f", "name=footer", "-F", "value=@"+path); err != nil {
+		t.Fatal(err)
+	}
+	if got["value"] != "<p>bye</p>" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestAPIPaginate(t *testing.T) {
+	cleanup := setupTestServer(t, pagedMessages(t))
+	defer cleanup()
+
+	out, err := executeCommand("api", "/v1/messages", "--paginate", "--jq", "[.messages[].id]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	if err := json.Unmarshal([]byte(out), &ids); err != nil || len(ids) != 5 {
+		t.Fatalf("got %s", out)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("dry run sent %s %s", r.Method, r.URL.Path)
+	}))
+	defer srv.Close()
+	orig := newClient
+	defer func() { newClient = orig }()
+	newClient = func() (*client.Client, error) {
+		c := &client.Client{BaseURL: srv.URL, Token: "test-token", HTTPClient: srv.Client()}
+		return c, configureClient(c, &config.Profile{})
+	}
+
+	out, err := executeCommand("snippets", "upsert", "--body", `{"name":"footer","value":"hi"}`, "--dry-run")
+	if !errors.Is(err, client.ErrDryRun) {
+		t.Fatalf("got %v, want ErrDryRun", err)
+	}
+	for _, want := range []string{"PUT " + srv.URL + "/v1/snippets\n", "Authorization: Bearer <redacted>\n", "{\n  \"name\": \"footer\","} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "test-token") {
+		t.Errorf("token leaked: %s", out)
+	}
+	if exitCode(client.ErrDryRun) != exitOK {
+		t.Error("dry run should exit 0")
+	}
+
+	out, err = executeCommand("snippets", "rm", "footer", "--dry-run=curl")
+	if !errors.Is(err, client.ErrDryRun) {
+		t.Fatalf("got %v, want ErrDryRun", err)
+	}
+	if !strings.HasPrefix(out, "curl -X DELETE '"+srv.URL+"/v1/snippets/footer'") {
+		t.Errorf("unexpected curl output: %s", out)
+	}
+
+	if _, err := executeCommand("snippets", "rm", "footer", "--dry-run=yaml"); err == nil {
+		t.Error("expected error for unknown dry-run format")
+	}
+}
+
+func TestConfirmRefusesWithoutTerminal(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+	})
+	defer cleanup()
+	orig := stdinIsTerminal
+	defer func() { stdinIsTerminal = orig }()
+	stdinIsTerminal = func() bool { return false }
+
+	_, err := executeCommand("webhooks", "rm", "4")
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Fatalf("got %v, want confirmation error", err)
+	}
+}
+
+func TestConfirmPrompt(t *testing.T) {
+	for _, tc := range []struct {
+		answer  string
+		deleted bool
+	}{{"y\n", true}, {"yes\n", true}, {"n\n", false}, {"\n", false}} {
+		deleted := false
+		cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodDelete:
+				deleted = true
+				w.WriteHeader(204)
+			case r.URL.Path == "/v1/segments/12":
+				_, _ = w.Write([]byte(`{"segment":{"id":12,"name":"VIP"}}`))
+			case r.URL.Path == "/v1/segments/12/customer_count":
+				_, _ = w.Write([]byte(`{"count":340}`))
+			default:
+				t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			}
+		})
+		origTTY, origIn, origErr := stdinIsTerminal, os.Stdin, os.Stderr
+		stdinIsTerminal = func() bool { return true }
+		in, inW, _ := os.Pipe()
+		_, _ = inW.WriteString(tc.answer)
+		inW.Close()
+		errR, errW, _ := os.Pipe()
+		os.Stdin, os.Stderr = in, errW
+
+		_, err := executeCommand("segments", "rm", "12")
+
+		errW.Close()
+		prompt, _ := io.ReadAll(errR)
+		stdinIsTerminal, os.Stdin, os.Stderr = origTTY, origIn, origErr
+		cleanup()
+
+		if want := "Delete segment 12 \"VIP\" (340 members)\nContinue? [y/N] "; string(prompt) != want {
+			t.Errorf("prompt = %q, want %q", prompt, want)
+		}
+		if deleted != tc.deleted {
+			t.Errorf("answer %q: deleted = %v", tc.answer, deleted)
+		}
+		if !tc.deleted && (err == nil || err.Error() != "aborted") {
+			t.Errorf("answer %q: got %v, want aborted", tc.answer, err)
+		}
+		if tc.deleted && err != nil {
+			t.Errorf("answer %q: %v", tc.answer, err)
+		}
+	}
+}
+
+func TestDestructiveCommandsTagged(t *testing.T) {
+	for _, path := range [][]string{
+		{"newsletters", "rm"}, {"collections", "rm"}, {"segments", "rm"}, {"webhooks", "rm"},
+		{"snippets", "rm"}, {"esp-suppression", "unsuppress"}, {"broadcasts", "trigger"},
+	} {
+		cmd, _, err := rootCmd.Find(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cmd.Annotations[annotationDestructive] != "true" {
+			t.Errorf("%s is not tagged destructive", cmd.CommandPath())
+		}
+	}
+}
+
+func TestAPIRejects(t *testing.T) {
+	cleanup := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+	defer cleanup()
+
+	for _, args := range [][]string{
+		{"api", "https://api.customer.io/v1/segments"},
+		{"api", "-X", "POST", "/v1/segments", "--paginate"},
+		{"api", "/v1/segments", "-f", "a[]b=1"},
+		{"api", "/v1/segments", "-f", "a=1", "--body", `{}`},
+	} {
+		if _, err := executeCommand(args...); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func captureBody(t *testing.T, got *[]byte) func() {
+	t.Helper()
+	return setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		*got, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{}`))
+	})
+}
+
+func TestBodyFromFile(t *testing.T) {
+	var got []byte
+	defer captureBody(t, &got)()
+
+	path := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(path, []byte(`{"to":"u@example.com"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand("send", "email", "--body", "@"+path); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"to":"u@example.com"}` {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestBodySetFlags(t *testing.T) {
+	var got []byte
+	defer captureBody(t, &got)()
+
+	html := filepath.Join(t.TempDir(), "body.html")
+	if err := os.WriteFile(html, []byte("<h1>Hi</h1>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := executeCommand("send", "email",
+		"--body", `{"to":"u@example.com","message_data":{"plan":"free","id":12345678901234567}}`,
+		"--set", "transactional_message_id=7",
+		"--set", "message_data.plan=pro",
+		"--set", "message_data.trial=false",
+		"--set", "message_data.coupon=null",
+		"--set", "message_data.ratio=0.5",
+		"--set", "subject=Hello world",
+		"--set-json", `message_data.tags=["a","b"]`,
+		"--set-json", `message_data.zip="02134"`,
+		"--set-file", "body=@"+html,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"body":"\u003ch1\u003eHi\u003c/h1\u003e","message_data":{"coupon":null,"id":12345678901234567,"plan":"pro","ratio":0.5,"tags":["a","b"],"trial":false,"zip":"02134"},"subject":"Hello world","to":"u@example.com","transactional_message_id":7}`
+	if string(got) != want {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestBodySetJSONMerges(t *testing.T) {
+	var got []byte
+	defer captureBody(t, &got)()
+
+	_, err := executeCommand("send", "email", "--body", `{"identifiers":{"id":"1"}}`, "--set-json", `identifiers={"email":"u@example.com"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"identifiers":{"email":"u@example.com","id":"1"}}` {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestBodyTemplate(t *testing.T) {
+	var got []byte
+	defer captureBody(t, &got)()
+
+	t.Setenv("CIO_TEST_RECIPIENT", `u"1@example.com`)
+	tmpl := filepath.Join(t.TempDir(), "email.tmpl")
+	src := `{"to":{{json (env "CIO_TEST_RECIPIENT")}},"transactional_message_id":{{.Env.CIO_TEST_MSG}}}`
+	if err := os.WriteFile(tmpl, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CIO_TEST_MSG", "3")
+	if _, err := executeCommand("send", "email", "--body-template", tmpl); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"to":"u\"1@example.com","transactional_message_id":3}` {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestBodyBuilderErrors(t *testing.T) {
+	var got []byte
+	defer captureBody(t, &got)()
+
+	for _, args := range [][]string{
+		{"send", "email", "--body", `[1]`, "--set", "a=1"},
+		{"send", "email", "--body", `{"a":1}`, "--set", "a.b=1"},
+		{"send", "email", "--set", "a..b=1"},
+		{"send", "email", "--set-json", "a=nope"},
+		{"send", "email", "--body", "{}", "--body-template", "x.tmpl"},
+	} {
+		if _, err := executeCommand(args...); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
 	}
 }
 
@@ -575,6 +2365,7 @@ func TestAllGroupsRegistered(t *testing.T) {
 		"index", "info", "messages", "newsletters", "objects",
 		"segments", "send", "sender-identities", "snippets",
 		"subscription-topics", "transactional", "webhooks", "workspaces",
+		"config", "auth", "track", "pipelines", "api", "audit", "cache", "filter",
 	}
 
 	cmds := make(map[string]bool)
@@ -604,7 +2395,7 @@ func TestSubcommandCounts(t *testing.T) {
 		{"collections", 6},
 		{"messages", 3},
 		{"send", 3},
-		{"exports", 5},
+		{"exports", 7},
 		{"webhooks", 4},
 		{"esp-suppression", 4},
 		{"sender-identities", 3},
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -21,7 +22,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/collections", nil)
+			data, err := c.Get(cmd.Context(), "/v1/collections", nil)
 			if err != nil {
 				return err
 			}
@@ -38,7 +39,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/collections/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/collections/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -58,7 +59,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Post("/v1/collections", body)
+			data, err := c.Post(cmd.Context(), "/v1/collections", body)
 			if err != nil {
 				return err
 			}
@@ -80,7 +81,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Put(fmt.Sprintf("/v1/collections/%s", args[0]), body)
+			data, err := c.Put(cmd.Context(), fmt.Sprintf("/v1/collections/%s", args[0]), body)
 			if err != nil {
 				return err
 			}
@@ -89,7 +90,7 @@ func init() {
 	}
 	addBodyFlag(update)
 
-	rm := &cobra.Command{
+	rm := destructive(&cobra.Command{
 		Use:     "rm <id>",
 		Aliases: []string{"delete"},
 		Short:   "Delete a collection",
@@ -99,13 +100,19 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Delete(fmt.Sprintf("/v1/collections/%s", args[0]), nil)
+			err = confirm(cmd, c, func(ctx context.Context) (string, error) {
+				return describeResource(ctx, c, "Delete", "collection", args[0], fmt.Sprintf("/v1/collections/%s", args[0]))
+			})
+			if err != nil {
+				return err
+			}
+			data, err := c.Delete(cmd.Context(), fmt.Sprintf("/v1/collections/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
 			return printJSON(data)
 		},
-	}
+	})
 
 	content := &cobra.Command{
 		Use:   "content <id>",
@@ -122,13 +129,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/leechael/cio/internal/client"
+	"github.com/leechael/cio/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	parent := &cobra.Command{
+		Use:   "config",
+		Short: "Manage configuration profiles",
+		Long: "Manage named configuration profiles stored in $XDG_CONFIG_HOME/cio/config.json\n" +
+			"(override the location with $CIO_CONFIG). Select a profile with --profile or $CIO_PROFILE.\n\n" +
+			"Keys: " + strings.Join(config.Keys, ", "),
+	}
+
+	ls := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			current, _ := cfg.ActiveName(profileName)
+			profiles := cfg.Profiles
+			if profiles == nil {
+				profiles = map[string]*config.Profile{}
+			}
+			if wantJSON() || jqExpr != "" {
+				return printObject(map[string]any{
+					"file":     cfg.File(),
+					"current":  current,
+					"profiles": profiles,
+				})
+			}
+			for _, name := range cfg.Names() {
+				marker := " "
+				if name == current {
+					marker = "*"
+				}
+				p := cfg.Profiles[name]
+				fmt.Printf("%s %s\tregion=%s\n", marker, name, valueOr(p.Region, "us"))
+			}
+			return nil
+		},
+	}
+
+	get := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Get a setting from the active profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			p, err := cfg.Active(profileName)
+			if err != nil {
+				return err
+			}
+			v, err := p.Get(args[0])
+			if err != nil {
+				return err
+			}
+			if wantJSON() {
+				return printObject(map[string]string{args[0]: v})
+			}
+			fmt.Println(v)
+			return nil
+		},
+	}
+
+	set := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a setting on the active profile (empty value clears it)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, value := args[0], args[1]
+			if class, ok := strings.CutPrefix(key, "rate_limits."); ok && value != "" {
+				if _, err := client.ParseRateLimits(map[string]string{class: value}); err != nil {
+					return err
+				}
+			}
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			name, _ := cfg.ActiveName(profileName)
+			if key == "read_only" {
+				if err := checkClearReadOnly(cfg, cfg.Profile(name), value); err != nil {
+					return err
+				}
+			}
+			if err := cfg.Profile(name).Set(key, value); err != nil {
+				return err
+			}
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+			fmt.Printf("Set %s on profile %s\n", key, name)
+			return nil
+		},
+	}
+
+	use := &cobra.Command{
+		Use:   "use <profile>",
+		Short: "Make a profile the current one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			target, ok := cfg.Profiles[args[0]]
+			if !ok {
+				return fmt.Errorf("profile %q not found", args[0])
+			}
+			if err := checkLeaveReadOnly(cfg, target); err != nil {
+				return err
+			}
+			cfg.CurrentProfile = args[0]
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+			fmt.Printf("Using profile %s\n", args[0])
+			return nil
+		},
+	}
+
+	current := &cobra.Command{
+		Use:   "current",
+		Short: "Show the active profile",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			name, source := cfg.ActiveName(profileName)
+			if wantJSON() {
+				return printObject(map[string]string{
+					"profile": name,
+					"source":  source,
+				})
+			}
+			fmt.Println(name)
+			return nil
+		},
+	}
+
+	parent.AddCommand(ls, get, set, use, current)
+	rootCmd.AddCommand(parent)
+}
+
+// checkClearReadOnly keeps read-only mode from being switched off from
+// inside it, whatever turned it on; --yes does not help, so an agent kept
+// in read-only mode cannot let itself out. The config file can still be
+// edited by hand.
+func checkClearReadOnly(cfg *config.Config, p *config.Profile, value string) error {
+	if on, err := strconv.ParseBool(value); value != "" && (err != nil || on) {
+		return nil
+	}
+	if on, source := readOnlyMode(p); on {
+		return fmt.Errorf("read-only mode is on (%s); read_only cannot be cleared from a read-only session, edit %s by hand", source, cfg.File())
+	}
+	return nil
+}
+
+// checkLeaveReadOnly keeps config use from making a profile without
+// read_only current from inside a read-only session.
+func checkLeaveReadOnly(cfg *config.Config, target *config.Profile) error {
+	p, err := cfg.Active(profileName)
+	if err != nil {
+		return err
+	}
+	if on, source := readOnlyMode(p); on && !target.ReadOnly {
+		return fmt.Errorf("read-only mode is on (%s) and profile %s is not read-only; edit %s by hand to switch", source, target.Name, cfg.File())
+	}
+	return nil
+}
+
+func valueOr(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/leechael/cio/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// annotationDestructive tags commands that delete data or send messages.
+// They ask for confirmation before running; see confirm.
+const annotationDestructive = "destructive"
+
+var assumeYes bool
+
+// stdinIsTerminal reports whether a confirmation prompt can be answered.
+var stdinIsTerminal = func() bool {
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "skip confirmation prompts for destructive commands")
+}
+
+// destructive tags cmd so it asks for confirmation and mentions --yes in
+// its help.
+func destructive(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[annotationDestructive] = "true"
+	long := cmd.Long
+	if long == "" {
+		long = cmd.Short + "."
+	}
+	cmd.Long = long + "\n\nAsks for confirmation first. --yes skips the prompt and is required\n" +
+		"when stdin is not a terminal."
+	return cmd
+}
+
+// confirm asks the user to approve a destructive action. describe fetches
+// what will be affected so the prompt can show it, e.g. a segment's name
+// and member count. --yes skips the prompt, as do dry-run and read-only
+// clients, which will not send the request; without a terminal the action
+// is refused.
+func confirm(cmd *cobra.Command, c *client.Client, describe func(ctx context.Context) (string, error)) error {
+	if assumeYes || c.DryRun != nil || c.ReadOnly {
+		return nil
+	}
+	refuse := fmt.Errorf("%s needs confirmation: pass --yes to run it non-interactively", cmd.CommandPath())
+	if !stdinIsTerminal() {
+		return refuse
+	}
+	what, err := describe(cmd.Context())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s\nContinue? [y/N] ", what)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err == io.EOF && line == "" {
+		// A character device that is immediately at EOF, such as
+		// /dev/null, cannot answer either.
+		fmt.Fprintln(os.Stderr)
+		return refuse
+	}
+	if err != nil && err != io.EOF {
+		return err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return nil
+	}
+	return errors.New("aborted")
+}
+
+// describeResource fetches path and renders "<verb> <kind> <id> "<name>"",
+// leaving the name out when the response has none.
+func describeResource(ctx context.Context, c *client.Client, verb, kind, id, path string) (string, error) {
+	data, err := c.Get(ctx, path, nil)
+	if err != nil {
+		return "", err
+	}
+	s := fmt.Sprintf("%s %s %s", verb, kind, id)
+	if name := resourceName(data); name != "" {
+		s += fmt.Sprintf(" %q", name)
+	}
+	return s, nil
+}
+
+// resourceName finds a "name" field at the top level of a response or in
+// the object it wraps, e.g. {"segment": {"name": ...}}.
+func resourceName(data json.RawMessage) string {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(data, &obj) != nil {
+		return ""
+	}
+	var name string
+	if json.Unmarshal(obj["name"], &name) == nil && name != "" {
+		return name
+	}
+	for _, v := range obj {
+		var inner struct{ Name string }
+		if json.Unmarshal(v, &inner) == nil && inner.Name != "" {
+			return inner.Name
+		}
+	}
+	return ""
+}
@@ -23,7 +23,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/customers/%s/attributes", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/customers/%s/attributes", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -40,9 +40,12 @@ func init() {
 				return err
 			}
 			email, _ := cmd.Flags().GetString("email")
+			if email != "" && cmd.Flags().Changed("where") {
+				return fmt.Errorf("--email and --where cannot be used together")
+			}
 			if email != "" {
 				q := url.Values{"email": {email}}
-				data, err := c.Get("/v1/customers", q)
+				data, err := c.Get(cmd.Context(), "/v1/customers", q)
 				if err != nil {
 					return err
 				}
@@ -52,10 +55,13 @@ func init() {
 			if err != nil {
 				return err
 			}
+			if body, err = applyWhere(cmd, body, "filter"); err != nil {
+				return err
+			}
 			if body == nil {
 				body = json.RawMessage(`{}`)
 			}
-			data, err := c.Post("/v1/customers", body)
+			data, err := c.Post(cmd.Context(), "/v1/customers", body)
 			if err != nil {
 				return err
 			}
@@ -64,6 +70,7 @@ func init() {
 	}
 	search.Flags().String("email", "", "Search by email address")
 	addBodyFlag(search)
+	addWhereFlag(search, "filter")
 
 	ls := &cobra.Command{
 		Use:     "ls",
@@ -81,7 +88,7 @@ func init() {
 			if body == nil {
 				body = json.RawMessage(`{}`)
 			}
-			data, err := c.Post("/v1/customers/attributes", body)
+			data, err := c.Post(cmd.Context(), "/v1/customers/attributes", body)
 			if err != nil {
 				return err
 			}
@@ -99,13 +106,10 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/customers/%s/activities", args[0]), nil)
-			if err != nil {
-				return err
-			}
-			return printJSON(data)
+			return listPages(cmd, c, fmt.Sprintf("/v1/customers/%s/activities", args[0]), nil)
 		},
 	}
+	addPageFlags(activities)
 
 	messages := &cobra.Command{
 		Use:   "messages <id>",
@@ -116,13 +120,10 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/customers/%s/messages", args[0]), nil)
-			if err != nil {
-				return err
-			}
-			return printJSON(data)
+			return listPages(cmd, c, fmt.Sprintf("/v1/customers/%s/messages", args[0]), nil)
 		},
 	}
+	addPageFlags(messages)
 
 	segments := &cobra.Command{
 		Use:   "segments <id>",
@@ -133,7 +134,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/customers/%s/segments", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/customers/%s/segments", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -150,7 +151,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/customers/%s/relationships", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/customers/%s/relationships", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -167,7 +168,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/customers/%s/subscription_preferences", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/customers/%s/subscription_preferences", args[0]), nil)
 			if err != nil {
 				return err
 			}
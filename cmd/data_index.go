@@ -18,7 +18,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/index/attributes", nil)
+			data, err := c.Get(cmd.Context(), "/v1/index/attributes", nil)
 			if err != nil {
 				return err
 			}
@@ -34,7 +34,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/index/events", nil)
+			data, err := c.Get(cmd.Context(), "/v1/index/events", nil)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/leechael/cio/internal/client"
+)
+
+// Exit codes are part of the CLI contract; scripts rely on them.
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitNotFound    = 3
+	exitAuth        = 4
+	exitRateLimited = 5
+	exitValidation  = 6
+	exitServer      = 7
+	exitInterrupted = 130
+)
+
+func exitCode(err error) int {
+	if err == nil || errors.Is(err, client.ErrDryRun) {
+		return exitOK
+	}
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return exitError
+	}
+	switch code := apiErr.StatusCode; {
+	case code == http.StatusNotFound:
+		return exitNotFound
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return exitAuth
+	case code == http.StatusTooManyRequests:
+		return exitRateLimited
+	case code == http.StatusBadRequest || code == http.StatusUnprocessableEntity:
+		return exitValidation
+	case code >= 500:
+		return exitServer
+	}
+	return exitError
+}
+
+type errorDocument struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Message  string `json:"message"`
+	ExitCode int    `json:"exit_code"`
+	*client.APIError
+}
+
+// writeError reports err on w, as a JSON document when JSON was asked for.
+func writeError(w io.Writer, err error) {
+	if !wantJSON() {
+		fmt.Fprintln(w, "Error:", err)
+		return
+	}
+	body := errorBody{Message: err.Error(), ExitCode: exitCode(err)}
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		body.Message = apiErr.Message()
+		body.APIError = apiErr
+	}
+	data, _ := json.MarshalIndent(errorDocument{Error: body}, "", "  ")
+	fmt.Fprintln(w, string(data))
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -28,7 +29,7 @@ func init() {
 			if body == nil {
 				body = json.RawMessage(`{}`)
 			}
-			data, err := c.Post("/v1/esp_suppression/search", body)
+			data, err := c.Post(cmd.Context(), "/v1/esp_suppression/search", body)
 			if err != nil {
 				return err
 			}
@@ -46,7 +47,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/esp_suppression/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/esp_suppression/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -67,7 +68,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Put(fmt.Sprintf("/v1/esp_suppression/%s", args[0]), body)
+			data, err := c.Put(cmd.Context(), fmt.Sprintf("/v1/esp_suppression/%s", args[0]), body)
 			if err != nil {
 				return err
 			}
@@ -76,7 +77,7 @@ func init() {
 	}
 	addBodyFlag(suppress)
 
-	unsuppress := &cobra.Command{
+	unsuppress := destructive(&cobra.Command{
 		Use:   "unsuppress <email>",
 		Short: "Unsuppress an email",
 		Args:  cobra.ExactArgs(1),
@@ -85,13 +86,22 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Delete(fmt.Sprintf("/v1/esp_suppression/%s", args[0]), nil)
+			err = confirm(cmd, c, func(ctx context.Context) (string, error) {
+				if _, err := c.Get(ctx, fmt.Sprintf("/v1/esp_suppression/%s", args[0]), nil); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Unsuppress %s so messages can be sent to it again", args[0]), nil
+			})
+			if err != nil {
+				return err
+			}
+			data, err := c.Delete(cmd.Context(), fmt.Sprintf("/v1/esp_suppression/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
 			return printJSON(data)
 		},
-	}
+	})
 
 	parent.AddCommand(search, get, suppress, unsuppress)
 	rootCmd.AddCommand(parent)
@@ -1,9 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/leechael/cio/internal/client"
+	"github.com/leechael/cio/internal/export"
 	"github.com/spf13/cobra"
 )
 
@@ -22,7 +30,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/exports", nil)
+			data, err := c.Get(cmd.Context(), "/v1/exports", nil)
 			if err != nil {
 				return err
 			}
@@ -42,10 +50,13 @@ func init() {
 			if err != nil {
 				return err
 			}
+			if body, err = applyWhere(cmd, body, "filters"); err != nil {
+				return err
+			}
 			if body == nil {
 				body = json.RawMessage(`{}`)
 			}
-			data, err := c.Post("/v1/exports/customers", body)
+			data, err := c.Post(cmd.Context(), "/v1/exports/customers", body)
 			if err != nil {
 				return err
 			}
@@ -53,6 +64,7 @@ func init() {
 		},
 	}
 	addBodyFlag(createCustomers)
+	addWhereFlag(createCustomers, "filters")
 
 	createDeliveries := &cobra.Command{
 		Use:   "create-deliveries",
@@ -69,7 +81,7 @@ func init() {
 			if body == nil {
 				body = json.RawMessage(`{}`)
 			}
-			data, err := c.Post("/v1/exports/deliveries", body)
+			data, err := c.Post(cmd.Context(), "/v1/exports/deliveries", body)
 			if err != nil {
 				return err
 			}
@@ -87,7 +99,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/exports/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/exports/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -98,20 +110,279 @@ func init() {
 	download := &cobra.Command{
 		Use:   "download <id>",
 		Short: "Download an export",
-		Args:  cobra.ExactArgs(1),
+		Long: "Without --file, prints the export's signed download URL.\n\n" +
+			"With --file, fetches the file and streams it to disk, gunzipping it on the\n" +
+			"fly. The download is checked against the size the server reports; if it is\n" +
+			"interrupted, running the same command again resumes from <file>.part.\n" +
+			"--format converts rows to csv or ndjson (one JSON object per row).",
+		Example: `  cio exports download 55
+  cio exports download 55 --file deliveries.csv
+  cio exports download 55 --file deliveries.ndjson --format ndjson`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("file")
+			format, _ := cmd.Flags().GetString("format")
+			if err := checkExportFormat(format); err != nil {
+				return err
+			}
+			if format != "" && path == "" {
+				return fmt.Errorf("--format requires --file")
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			if path != "" {
+				return downloadExport(cmd.Context(), c, args[0], path, format)
+			}
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/exports/%s/download", args[0]), nil)
+			if err != nil {
+				return err
+			}
+			return printJSON(data)
+		},
+	}
+	download.Flags().String("file", "", "write the export file here instead of printing its URL")
+	download.Flags().String("format", "", "convert rows to csv or ndjson")
+
+	wait := &cobra.Command{
+		Use:   "wait <id>",
+		Short: "Wait for an export to finish",
+		Long: "Polls the export, backing off from 2s to 30s between checks, until it is\n" +
+			"done or has failed, then prints it. A failed export, or one still running\n" +
+			"after --wait-timeout, exits non-zero.",
+		Example: "  cio exports wait 55 --wait-timeout 10m",
+		Args:    cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c, err := newClient()
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/exports/%s/download", args[0]), nil)
+			limit, _ := cmd.Flags().GetDuration("wait-timeout")
+			data, err := waitForExport(cmd.Context(), c, args[0], limit)
 			if err != nil {
 				return err
 			}
 			return printJSON(data)
 		},
 	}
+	addWaitTimeoutFlag(wait)
+
+	run := &cobra.Command{
+		Use:   "run <customers|deliveries>",
+		Short: "Create an export, wait for it and download it",
+		Long: "Creates a customers or deliveries export, waits until it is done and\n" +
+			"downloads it to --file, gunzipped, as exports download does. The body\n" +
+			"flags take the same request body as create-customers and\n" +
+			"create-deliveries; --filter (JSON) or --where (an expression, see cio\n" +
+			"filter compile) sets the customers export's filters.\n" +
+			"A failed export exits non-zero with the reason the API gives.",
+		Example: `  cio exports run customers --filter '{"and":[{"segment":{"id":7}}]}' --file customers.csv
+  cio exports run customers --where 'segment=7 and email exists' --file customers.csv
+  cio exports run deliveries --body '{"newsletter_id":12}' --file deliveries.ndjson --format ndjson`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"customers", "deliveries"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind := args[0]
+			if kind != "customers" && kind != "deliveries" {
+				return fmt.Errorf("invalid export type %q (want customers or deliveries)", kind)
+			}
+			path, _ := cmd.Flags().GetString("file")
+			format, _ := cmd.Flags().GetString("format")
+			if err := checkExportFormat(format); err != nil {
+				return err
+			}
+			body, err := exportRunBody(cmd, kind)
+			if err != nil {
+				return err
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			data, err := c.Post(cmd.Context(), "/v1/exports/"+kind, body)
+			if err != nil {
+				return err
+			}
+			var created struct {
+				Export struct {
+					ID json.Number `json:"id"`
+				} `json:"export"`
+			}
+			if err := json.Unmarshal(data, &created); err != nil || created.Export.ID == "" {
+				return fmt.Errorf("no export id in the response: %s", data)
+			}
+			id := created.Export.ID.String()
+			fmt.Fprintf(os.Stderr, "Created export %s\n", id)
+
+			limit, _ := cmd.Flags().GetDuration("wait-timeout")
+			if _, err := waitForExport(cmd.Context(), c, id, limit); err != nil {
+				return err
+			}
+			return downloadExport(cmd.Context(), c, id, path, format)
+		},
+	}
+	addBodyFlag(run)
+	addWaitTimeoutFlag(run)
+	run.Flags().String("filter", "", "customers export filters as JSON, or @file")
+	addWhereFlag(run, "filters")
+	run.Flags().String("file", "", "write the export file here")
+	run.Flags().String("format", "", "convert rows to csv or ndjson")
+	_ = run.MarkFlagRequired("file")
 
-	parent.AddCommand(ls, createCustomers, createDeliveries, get, download)
+	parent.AddCommand(ls, createCustomers, createDeliveries, get, download, wait, run)
 	rootCmd.AddCommand(parent)
 }
+
+func addWaitTimeoutFlag(cmd *cobra.Command) {
+	cmd.Flags().Duration("wait-timeout", 30*time.Minute, "give up waiting for the export after this long (0 waits forever)")
+}
+
+func checkExportFormat(format string) error {
+	if format != "" && format != export.CSV && format != export.NDJSON {
+		return fmt.Errorf("invalid --format %q (want csv or ndjson)", format)
+	}
+	return nil
+}
+
+// exportRunBody is the create request for exports run: the body flags,
+// with --filter or --where set as "filters".
+func exportRunBody(cmd *cobra.Command, kind string) (json.RawMessage, error) {
+	body, err := readBody(cmd)
+	if err != nil {
+		return nil, err
+	}
+	filter, _ := cmd.Flags().GetString("filter")
+	where, _ := cmd.Flags().GetString("where")
+	switch {
+	case filter == "" && where == "":
+		if body == nil {
+			body = json.RawMessage(`{}`)
+		}
+		return body, nil
+	case kind != "customers":
+		return nil, fmt.Errorf("--filter and --where only apply to customers exports")
+	case filter != "" && where != "":
+		return nil, fmt.Errorf("--filter and --where cannot be used together")
+	case where != "":
+		return applyWhere(cmd, body, "filters")
+	}
+	raw := []byte(filter)
+	if file, ok := strings.CutPrefix(filter, "@"); ok {
+		if raw, err = readFileArg(file); err != nil {
+			return nil, err
+		}
+	}
+	filters, err := decodeJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("--filter is not valid JSON: %w", err)
+	}
+	return setBodyField(body, "filters", filters)
+}
+
+// Polling intervals for waitForExport; tests shorten them.
+var (
+	exportPollInterval = 2 * time.Second
+	exportPollMax      = 30 * time.Second
+)
+
+// waitForExport polls an export until it is done and returns the last
+// response. A failed export is an error carrying the API's reason.
+func waitForExport(ctx context.Context, c *client.Client, id string, limit time.Duration) (json.RawMessage, error) {
+	if limit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limit)
+		defer cancel()
+	}
+	bar := newProgressBar("export " + id)
+	defer bar.finish()
+	start := time.Now()
+	interval := exportPollInterval
+	status := ""
+	for {
+		data, err := c.Get(ctx, fmt.Sprintf("/v1/exports/%s", id), nil)
+		if err != nil {
+			if limit > 0 && errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("export %s is not done after %s (status %s)", id, limit, valueOr(status, "unknown"))
+			}
+			return nil, err
+		}
+		var state exportState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("unexpected export response: %w", err)
+		}
+		status = state.Export.Status
+		switch {
+		case state.failed():
+			return nil, fmt.Errorf("export %s failed: %s", id, state.reason())
+		case status == "done":
+			return data, nil
+		}
+		bar.status(fmt.Sprintf("%s, waited %s", valueOr(status, "pending"), time.Since(start).Round(time.Second)))
+
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			if limit > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("export %s is not done after %s (status %s)", id, limit, valueOr(status, "unknown"))
+			}
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		interval = min(interval*3/2, exportPollMax)
+	}
+}
+
+type exportState struct {
+	Export struct {
+		Status        string `json:"status"`
+		Failed        bool   `json:"failed"`
+		Error         string `json:"error"`
+		FailureReason string `json:"failure_reason"`
+	} `json:"export"`
+}
+
+func (s exportState) failed() bool {
+	return s.Export.Failed || s.Export.Status == "failed"
+}
+
+func (s exportState) reason() string {
+	switch {
+	case s.Export.FailureReason != "":
+		return s.Export.FailureReason
+	case s.Export.Error != "":
+		return s.Export.Error
+	}
+	return "the API gave no reason"
+}
+
+// downloadExport fetches an export's file to path, see exports download.
+func downloadExport(ctx context.Context, c *client.Client, id, path, format string) error {
+	data, err := c.Get(ctx, fmt.Sprintf("/v1/exports/%s/download", id), nil)
+	if err != nil {
+		return err
+	}
+	var link struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(data, &link); err != nil || link.URL == "" {
+		return fmt.Errorf("no download URL in the response for export %s", id)
+	}
+	bar := newProgressBar("export " + id)
+	err = c.Download(ctx, client.Download{
+		URL:  link.URL,
+		Path: path,
+		Transform: func(dst io.Writer, src io.Reader) error {
+			return export.Convert(dst, src, format)
+		},
+		Progress: bar.update,
+	})
+	bar.finish()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Saved export %s to %s\n", id, path)
+	return nil
+}
@@ -24,7 +24,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Post("/v1/imports", body)
+			data, err := c.Post(cmd.Context(), "/v1/imports", body)
 			if err != nil {
 				return err
 			}
@@ -42,7 +42,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/imports/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/imports/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
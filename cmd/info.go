@@ -18,7 +18,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/info/ip_addresses", nil)
+			data, err := c.Get(cmd.Context(), "/v1/info/ip_addresses", nil)
 			if err != nil {
 				return err
 			}
@@ -21,13 +21,10 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/messages", nil)
-			if err != nil {
-				return err
-			}
-			return printJSON(data)
+			return listPages(cmd, c, "/v1/messages", nil)
 		},
 	}
+	addPageFlags(ls)
 
 	get := &cobra.Command{
 		Use:   "get <id>",
@@ -38,7 +35,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/messages/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/messages/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -55,7 +52,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/messages/%s/archived_message", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/messages/%s/archived_message", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 
+	"github.com/leechael/cio/internal/client"
 	"github.com/spf13/cobra"
 )
 
@@ -21,7 +25,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/newsletters", nil)
+			data, err := c.Get(cmd.Context(), "/v1/newsletters", nil)
 			if err != nil {
 				return err
 			}
@@ -38,7 +42,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/newsletters/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/newsletters/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -46,7 +50,7 @@ func init() {
 		},
 	}
 
-	rm := &cobra.Command{
+	rm := destructive(&cobra.Command{
 		Use:     "rm <id>",
 		Aliases: []string{"delete"},
 		Short:   "Delete a newsletter",
@@ -56,13 +60,19 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Delete(fmt.Sprintf("/v1/newsletters/%s", args[0]), nil)
+			err = confirm(cmd, c, func(ctx context.Context) (string, error) {
+				return describeResource(ctx, c, "Delete", "newsletter", args[0], fmt.Sprintf("/v1/newsletters/%s", args[0]))
+			})
+			if err != nil {
+				return err
+			}
+			data, err := c.Delete(cmd.Context(), fmt.Sprintf("/v1/newsletters/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
 			return printJSON(data)
 		},
-	}
+	})
 
 	contents := &cobra.Command{
 		Use:   "contents <id>",
@@ -73,7 +83,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/newsletters/%s/contents", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/newsletters/%s/contents", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -96,13 +106,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
@@ -120,7 +130,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/newsletters/%s/metrics", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/newsletters/%s/metrics", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -137,7 +147,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/newsletters/%s/metrics/links", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/newsletters/%s/metrics/links", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -154,7 +164,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/newsletters/%s/contents/%s/metrics", args[0], args[1]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/newsletters/%s/contents/%s/metrics", args[0], args[1]), nil)
 			if err != nil {
 				return err
 			}
@@ -171,7 +181,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/newsletters/%s/contents/%s/metrics/links", args[0], args[1]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/newsletters/%s/contents/%s/metrics/links", args[0], args[1]), nil)
 			if err != nil {
 				return err
 			}
@@ -188,13 +198,10 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/newsletters/%s/messages", args[0]), nil)
-			if err != nil {
-				return err
-			}
-			return printJSON(data)
+			return listPages(cmd, c, fmt.Sprintf("/v1/newsletters/%s/messages", args[0]), nil)
 		},
 	}
+	addPageFlags(nlMessages)
 
 	nlTranslation := &cobra.Command{
 		Use:   "translation <id> <lang>",
@@ -211,13 +218,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
@@ -229,19 +236,28 @@ func init() {
 	testGroups := &cobra.Command{
 		Use:   "test-groups <id>",
 		Short: "Get newsletter test groups",
-		Args:  cobra.ExactArgs(1),
+		Long: "Get newsletter test groups. Test groups have no metrics endpoint of their\n" +
+			"own; --metrics attaches the metrics of each group's variants, keyed by\n" +
+			"content ID.",
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c, err := newClient()
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/newsletters/%s/test_groups", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/newsletters/%s/test_groups", args[0]), nil)
 			if err != nil {
 				return err
 			}
+			if withMetrics, _ := cmd.Flags().GetBool("metrics"); withMetrics {
+				if data, err = testGroupMetrics(cmd.Context(), c, args[0], data); err != nil {
+					return err
+				}
+			}
 			return printJSON(data)
 		},
 	}
+	testGroups.Flags().Bool("metrics", false, "Include each test group's variant metrics")
 
 	testGroupTranslation := &cobra.Command{
 		Use:   "test-group-translation <id> <group-id> <lang>",
@@ -258,13 +274,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
@@ -276,3 +292,58 @@ func init() {
 	parent.AddCommand(ls, get, rm, contents, content, nlMetrics, nlLinkMetrics, contentMetrics, contentLinkMetrics, nlMessages, nlTranslation, testGroups, testGroupTranslation)
 	rootCmd.AddCommand(parent)
 }
+
+// newsletterTestGroup is the part of a test group from
+// GET /v1/newsletters/{id}/test_groups that --metrics needs.
+type newsletterTestGroup struct {
+	ID         string `json:"id"`
+	ContentIDs []int  `json:"content_ids"`
+}
+
+// testGroupMetrics adds a "metrics" object to every test group, mapping
+// each of the group's content IDs to that variant's metrics. Other fields
+// are passed through as they came.
+func testGroupMetrics(ctx context.Context, c *client.Client, newsletterID string, data json.RawMessage) (json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("test groups response: %w", err)
+	}
+	if doc["test_groups"] == nil {
+		return nil, fmt.Errorf("test groups response has no \"test_groups\"")
+	}
+	// groups keeps every field for the output; typed has the ones used.
+	var (
+		groups []map[string]json.RawMessage
+		typed  []newsletterTestGroup
+	)
+	if err := json.Unmarshal(doc["test_groups"], &groups); err != nil {
+		return nil, fmt.Errorf("test groups response: \"test_groups\": %w", err)
+	}
+	if err := json.Unmarshal(doc["test_groups"], &typed); err != nil {
+		return nil, fmt.Errorf("test groups response: \"test_groups\": %w", err)
+	}
+	for i, group := range typed {
+		if groups[i] == nil || groups[i]["content_ids"] == nil {
+			return nil, fmt.Errorf("test group %q has no \"content_ids\"", group.ID)
+		}
+		metrics := make(map[string]json.RawMessage, len(group.ContentIDs))
+		for _, id := range group.ContentIDs {
+			m, err := c.Get(ctx, fmt.Sprintf("/v1/newsletters/%s/contents/%d/metrics", newsletterID, id), nil)
+			if err != nil {
+				return nil, fmt.Errorf("metrics for content %d: %w", id, err)
+			}
+			metrics[strconv.Itoa(id)] = m
+		}
+		raw, err := json.Marshal(metrics)
+		if err != nil {
+			return nil, err
+		}
+		groups[i]["metrics"] = raw
+	}
+	raw, err := json.Marshal(groups)
+	if err != nil {
+		return nil, err
+	}
+	doc["test_groups"] = raw
+	return json.Marshal(doc)
+}
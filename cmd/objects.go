@@ -21,7 +21,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/object_types", nil)
+			data, err := c.Get(cmd.Context(), "/v1/object_types", nil)
 			if err != nil {
 				return err
 			}
@@ -41,10 +41,13 @@ func init() {
 			if err != nil {
 				return err
 			}
+			if body, err = applyWhere(cmd, body, "filter"); err != nil {
+				return err
+			}
 			if body == nil {
 				body = json.RawMessage(`{}`)
 			}
-			data, err := c.Post("/v1/objects", body)
+			data, err := c.Post(cmd.Context(), "/v1/objects", body)
 			if err != nil {
 				return err
 			}
@@ -52,6 +55,7 @@ func init() {
 		},
 	}
 	addBodyFlag(search)
+	addWhereFlag(search, "filter")
 
 	get := &cobra.Command{
 		Use:   "get <type-id> <object-id>",
@@ -62,7 +66,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/objects/%s/%s/attributes", args[0], args[1]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/objects/%s/%s/attributes", args[0], args[1]), nil)
 			if err != nil {
 				return err
 			}
@@ -79,7 +83,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/objects/%s/%s/relationships", args[0], args[1]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/objects/%s/%s/relationships", args[0], args[1]), nil)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/leechael/cio/internal/client"
+	"github.com/spf13/cobra"
+)
+
+func addPageFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("all", false, "Fetch every page")
+	cmd.Flags().Int("limit", 0, "Return at most N items, following pages as needed")
+	cmd.Flags().Int("page-size", 0, "Number of items per page")
+	cmd.Flags().String("start", "", "Pagination cursor to start from")
+	cmd.Flags().Bool("stream", false, "Print each page as it arrives instead of merging")
+}
+
+type pageOptions struct {
+	all      bool
+	limit    int
+	pageSize int
+	start    string
+	stream   bool
+}
+
+// listPages fetches a cursor-paginated list according to the page flags.
+func listPages(cmd *cobra.Command, c *client.Client, path string, query url.Values) error {
+	var opts pageOptions
+	opts.all, _ = cmd.Flags().GetBool("all")
+	opts.limit, _ = cmd.Flags().GetInt("limit")
+	opts.pageSize, _ = cmd.Flags().GetInt("page-size")
+	opts.start, _ = cmd.Flags().GetString("start")
+	opts.stream, _ = cmd.Flags().GetBool("stream")
+	return fetchPages(cmd.Context(), c, path, query, opts)
+}
+
+// fetchPages prints a cursor-paginated list. Without all or limit only the
+// first page is fetched. Merged output keeps the first page's scalar
+// fields, concatenates every array field and carries the last page's
+// "next" cursor so the listing can be resumed. CSV, TSV and NDJSON rows
+// need no merging and stream unless --sort-by has to see every page.
+func fetchPages(ctx context.Context, c *client.Client, path string, query url.Values, opts pageOptions) error {
+	if opts.limit < 0 || opts.pageSize < 0 {
+		return fmt.Errorf("--limit and --page-size must not be negative")
+	}
+	if recordOutput() {
+		if opts.stream && sortBy != "" {
+			return fmt.Errorf("--sort-by cannot be used with --stream and --output %s: sorting needs every page", outputFormat)
+		}
+		opts.stream = sortBy == ""
+	}
+
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	if opts.start != "" {
+		q.Set("start", opts.start)
+	}
+	if opts.pageSize > 0 {
+		q.Set("limit", strconv.Itoa(opts.pageSize))
+	}
+
+	var (
+		first     json.RawMessage
+		merged    *pageSet
+		pages     int
+		remaining = opts.limit
+	)
+	for data, err := range c.Pages(ctx, path, q) {
+		if err != nil {
+			if pages == 0 {
+				return err
+			}
+			// Keep what was fetched so an interrupted --all run is not
+			// wasted; the error still makes the exit status non-zero.
+			if !opts.stream {
+				if perr := printPage(merged, nil); perr != nil {
+					return perr
+				}
+			}
+			return fmt.Errorf("stopped after %d page(s), output is partial: %w", pages, err)
+		}
+		pages++
+
+		page, err := decodePage(data)
+		if err != nil {
+			if pages == 1 {
+				return printJSON(data)
+			}
+			return err
+		}
+
+		done := !opts.all && opts.limit == 0
+		if opts.limit > 0 {
+			if n := page.len(); n >= remaining {
+				page.truncate(remaining)
+				done = true
+			}
+			remaining -= page.len()
+		}
+
+		if opts.stream {
+			if err := printPage(page, data); err != nil {
+				return err
+			}
+		} else if merged == nil {
+			first, merged = data, page
+		} else {
+			merged.append(page)
+		}
+
+		if done {
+			break
+		}
+	}
+
+	if opts.stream || merged == nil {
+		return nil
+	}
+	if pages > 1 {
+		first = nil
+	}
+	return printPage(merged, first)
+}
+
+// pageSet is a decoded list response split into array and scalar fields.
+type pageSet struct {
+	fields    map[string]json.RawMessage
+	arrays    map[string][]json.RawMessage
+	truncated bool
+}
+
+func decodePage(data json.RawMessage) (*pageSet, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	p := &pageSet{fields: make(map[string]json.RawMessage), arrays: make(map[string][]json.RawMessage)}
+	for k, v := range raw {
+		if trimmed := bytes.TrimSpace(v); len(trimmed) > 0 && trimmed[0] == '[' {
+			var items []json.RawMessage
+			if err := json.Unmarshal(v, &items); err != nil {
+				return nil, err
+			}
+			p.arrays[k] = items
+			continue
+		}
+		p.fields[k] = v
+	}
+	return p, nil
+}
+
+// len is the item count of the page. Some endpoints return parallel arrays
+// (segment membership has both "identifiers" and "ids"), so the longest
+// one wins.
+func (p *pageSet) len() int {
+	n := 0
+	for _, items := range p.arrays {
+		n = max(n, len(items))
+	}
+	return n
+}
+
+func (p *pageSet) truncate(n int) {
+	for k, items := range p.arrays {
+		if len(items) > n {
+			p.arrays[k] = items[:n]
+			p.truncated = true
+		}
+	}
+}
+
+func (p *pageSet) append(next *pageSet) {
+	for k, items := range next.arrays {
+		p.arrays[k] = append(p.arrays[k], items...)
+	}
+	if v, ok := next.fields["next"]; ok {
+		p.fields["next"] = v
+	} else {
+		delete(p.fields, "next")
+	}
+	p.truncated = next.truncated
+}
+
+// printPage prints the page. When original is given and nothing was cut,
+// it is printed as-is so the API's key order is preserved.
+func printPage(p *pageSet, original json.RawMessage) error {
+	if original != nil && !p.truncated {
+		return printJSON(original)
+	}
+	out := make(map[string]any, len(p.fields)+len(p.arrays))
+	for k, v := range p.fields {
+		out[k] = v
+	}
+	if p.truncated {
+		// The cursor points past items we dropped, so resuming from it would
+		// skip them.
+		delete(out, "next")
+	}
+	for k, items := range p.arrays {
+		if items == nil {
+			items = []json.RawMessage{}
+		}
+		out[k] = items
+	}
+	return printObject(out)
+}
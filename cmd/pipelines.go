@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	parent := &cobra.Command{
+		Use:   "pipelines",
+		Short: "Send data to the Pipelines (CDP) API",
+		Long: "Send identify, track, group, page, screen, alias and batch calls to the\n" +
+			"Pipelines API. Authenticates with $CUSTOMERIO_WRITE_KEY, or the profile's\n" +
+			"write_key_env and write_key_command.\n\n" +
+			"Payloads can be given as JSON via --body or stdin; flags are applied on\n" +
+			"top, so --user-id overrides the body's userId.",
+	}
+
+	identify := &cobra.Command{
+		Use:   "identify",
+		Short: "Identify a person and set traits",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			payload, err := pipelinesPayload(cmd, "traits")
+			if err != nil {
+				return err
+			}
+			if err := requireUser(payload); err != nil {
+				return err
+			}
+			return sendPipelines(cmd, "/v1/identify", payload)
+		},
+	}
+	addPipelinesFlags(identify, "trait")
+
+	track := &cobra.Command{
+		Use:   "track",
+		Short: "Record an event",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			payload, err := pipelinesPayload(cmd, "properties")
+			if err != nil {
+				return err
+			}
+			setFlag(cmd, payload, "event", "event")
+			if err := requireUser(payload); err != nil {
+				return err
+			}
+			if err := requireField(payload, "event", "--event"); err != nil {
+				return err
+			}
+			return sendPipelines(cmd, "/v1/track", payload)
+		},
+	}
+	addPipelinesFlags(track, "property")
+	track.Flags().String("event", "", "Event name")
+
+	group := &cobra.Command{
+		Use:   "group",
+		Short: "Associate a person with a group (object)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			payload, err := pipelinesPayload(cmd, "traits")
+			if err != nil {
+				return err
+			}
+			setFlag(cmd, payload, "group-id", "groupId")
+			if err := requireUser(payload); err != nil {
+				return err
+			}
+			if err := requireField(payload, "groupId", "--group-id"); err != nil {
+				return err
+			}
+			return sendPipelines(cmd, "/v1/group", payload)
+		},
+	}
+	addPipelinesFlags(group, "trait")
+	group.Flags().String("group-id", "", "Group ID")
+
+	page := &cobra.Command{
+		Use:   "page",
+		Short: "Record a page view",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			payload, err := pipelinesPayload(cmd, "properties")
+			if err != nil {
+				return err
+			}
+			setFlag(cmd, payload, "name", "name")
+			if err := requireUser(payload); err != nil {
+				return err
+			}
+			return sendPipelines(cmd, "/v1/page", payload)
+		},
+	}
+	addPipelinesFlags(page, "property")
+	page.Flags().String("name", "", "Page name")
+
+	screen := &cobra.Command{
+		Use:   "screen",
+		Short: "Record a mobile screen view",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			payload, err := pipelinesPayload(cmd, "properties")
+			if err != nil {
+				return err
+			}
+			setFlag(cmd, payload, "name", "name")
+			if err := requireUser(payload); err != nil {
+				return err
+			}
+			return sendPipelines(cmd, "/v1/screen", payload)
+		},
+	}
+	addPipelinesFlags(screen, "property")
+	screen.Flags().String("name", "", "Screen name")
+
+	alias := &cobra.Command{
+		Use:   "alias",
+		Short: "Merge a previous identity into a user ID",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			payload, err := pipelinesPayload(cmd, "")
+			if err != nil {
+				return err
+			}
+			setFlag(cmd, payload, "previous-id", "previousId")
+			if err := requireField(payload, "userId", "--user-id"); err != nil {
+				return err
+			}
+			if err := requireField(payload, "previousId", "--previous-id"); err != nil {
+				return err
+			}
+			return sendPipelines(cmd, "/v1/alias", payload)
+		},
+	}
+	addBodyFlag(alias)
+	alias.Flags().String("user-id", "", "User ID")
+	alias.Flags().String("previous-id", "", "Previous user or anonymous ID")
+	alias.Flags().String("timestamp", "", "ISO 8601 time of the call (default now)")
+
+	batch := &cobra.Command{
+		Use:   "batch",
+		Short: "Send several calls in one request",
+		Long: "Send several calls in one request. The body is either {\"batch\": [...]}\n" +
+			"or a bare array of calls, each with a \"type\" such as identify or track.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := requireBody(cmd)
+			if err != nil {
+				return err
+			}
+			var calls []json.RawMessage
+			if json.Unmarshal(body, &calls) == nil {
+				body, err = json.Marshal(map[string]any{"batch": calls})
+				if err != nil {
+					return err
+				}
+			}
+			payload, err := decodeObject(body)
+			if err != nil {
+				return err
+			}
+			if _, ok := payload["batch"].([]any); !ok {
+				return fmt.Errorf("body must contain a \"batch\" array")
+			}
+			return sendPipelines(cmd, "/v1/batch", payload)
+		},
+	}
+	addBodyFlag(batch)
+
+	parent.AddCommand(identify, track, group, page, screen, alias, batch)
+	rootCmd.AddCommand(parent)
+}
+
+// addPipelinesFlags adds the flags shared by identify, track, group, page
+// and screen. field names the repeatable key=value flag: trait or property.
+func addPipelinesFlags(cmd *cobra.Command, field string) {
+	addBodyFlag(cmd)
+	cmd.Flags().String("user-id", "", "User ID")
+	cmd.Flags().String("anonymous-id", "", "Anonymous ID")
+	cmd.Flags().String("timestamp", "", "ISO 8601 time of the call (default now)")
+	cmd.Flags().StringArray(field, nil, fmt.Sprintf("Set a %s as key=value (repeatable)", field))
+}
+
+// pipelinesPayload starts from the JSON body, if any, and applies the
+// common flags. object names the payload field that --trait or --property
+// values are merged into.
+func pipelinesPayload(cmd *cobra.Command, object string) (map[string]any, error) {
+	body, err := readBody(cmd)
+	if err != nil {
+		return nil, err
+	}
+	payload := make(map[string]any)
+	if body != nil {
+		if payload, err = decodeObject(body); err != nil {
+			return nil, err
+		}
+	}
+	setFlag(cmd, payload, "user-id", "userId")
+	setFlag(cmd, payload, "anonymous-id", "anonymousId")
+	setFlag(cmd, payload, "timestamp", "timestamp")
+
+	if object == "" {
+		return payload, nil
+	}
+	flag := "trait"
+	if object == "properties" {
+		flag = "property"
+	}
+	pairs, _ := cmd.Flags().GetStringArray(flag)
+	if len(pairs) == 0 {
+		return payload, nil
+	}
+	values, ok := payload[object].(map[string]any)
+	if !ok {
+		if payload[object] != nil {
+			return nil, fmt.Errorf("body %q must be a JSON object", object)
+		}
+		values = make(map[string]any)
+	}
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid --%s %q (want key=value)", flag, pair)
+		}
+		values[k] = v
+	}
+	payload[object] = values
+	return payload, nil
+}
+
+// decodeObject decodes a JSON object, keeping numbers as json.Number so
+// large IDs survive the round trip.
+func decodeObject(data []byte) (map[string]any, error) {
+	var obj map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil || obj == nil {
+		return nil, fmt.Errorf("body must be a JSON object")
+	}
+	return obj, nil
+}
+
+func setFlag(cmd *cobra.Command, payload map[string]any, flag, key string) {
+	if v, _ := cmd.Flags().GetString(flag); v != "" {
+		payload[key] = v
+	}
+}
+
+func requireUser(payload map[string]any) error {
+	if payload["userId"] == nil && payload["anonymousId"] == nil {
+		return fmt.Errorf("--user-id or --anonymous-id is required")
+	}
+	return nil
+}
+
+func requireField(payload map[string]any, key, flag string) error {
+	if payload[key] == nil {
+		return fmt.Errorf("%s is required", flag)
+	}
+	return nil
+}
+
+func sendPipelines(cmd *cobra.Command, path string, payload map[string]any) error {
+	c, err := newPipelinesClient()
+	if err != nil {
+		return err
+	}
+	data, err := c.Post(cmd.Context(), path, payload)
+	if err != nil {
+		return err
+	}
+	return printJSON(data)
+}
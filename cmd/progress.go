@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// stderrIsTerminal reports whether progress can be drawn in place.
+var stderrIsTerminal = func() bool {
+	stat, err := os.Stderr.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// progressBar redraws one status line at most every 100ms.
+type progressBar struct {
+	w     io.Writer
+	label string
+	last  time.Time
+	drawn bool
+}
+
+// newProgressBar returns nil, which draws nothing, when stderr is not a
+// terminal.
+func newProgressBar(label string) *progressBar {
+	if !stderrIsTerminal() {
+		return nil
+	}
+	return &progressBar{w: os.Stderr, label: label}
+}
+
+func (p *progressBar) update(done, total int64) {
+	if p == nil || (p.drawn && time.Since(p.last) < 100*time.Millisecond && done != total) {
+		return
+	}
+	p.last, p.drawn = time.Now(), true
+	if total <= 0 {
+		fmt.Fprintf(p.w, "\r%s %s\x1b[K", p.label, formatBytes(done))
+		return
+	}
+	const width = 30
+	filled := min(int(done*width/total), width)
+	fmt.Fprintf(p.w, "\r%s [%s%s] %3d%% %s / %s\x1b[K", p.label,
+		strings.Repeat("=", filled), strings.Repeat(" ", width-filled),
+		done*100/total, formatBytes(done), formatBytes(total))
+}
+
+// status replaces the line with a short text, such as a job's state.
+func (p *progressBar) status(text string) {
+	if p == nil {
+		return
+	}
+	p.last, p.drawn = time.Now(), true
+	fmt.Fprintf(p.w, "\r%s %s\x1b[K", p.label, text)
+}
+
+func (p *progressBar) finish() {
+	if p != nil && p.drawn {
+		fmt.Fprintln(p.w)
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -1,26 +1,60 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"time"
 
 	"github.com/leechael/cio/internal/client"
+	"github.com/leechael/cio/internal/config"
 	"github.com/leechael/cio/internal/output"
 	"github.com/spf13/cobra"
 )
 
 var (
 	region      string
+	profileName string
+	tokenFile   string
 	jqExpr      string
 	jsonOutput  bool
 	plainOutput bool
+
+	outputFormat string
+	columns      []string
+	sortBy       string
+	noHeaders    bool
+	records      *output.RecordWriter
+
+	retries      int
+	retryMaxWait time.Duration
+	rateLimits   map[string]string
+	timeout      time.Duration
+	dryRun       string
+	readOnly     bool
+
+	verbose    bool
+	traceHTTP  bool
+	traceFile  string
+	traceSpans *client.SpanFile
+
+	recordDir string
+	replayDir string
+	replayer  *client.Replayer
+
+	noCache      bool
+	refreshCache bool
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "cio",
-	Short: "CLI for Customer.io App API",
+	Use:           "cio",
+	Short:         "CLI for Customer.io App API",
+	SilenceErrors: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		if jsonOutput && plainOutput {
 			return fmt.Errorf("--json and --plain cannot be used together")
@@ -28,71 +62,380 @@ var rootCmd = &cobra.Command{
 		if jqExpr != "" && plainOutput {
 			return fmt.Errorf("--jq requires JSON output mode (remove --plain or use --json)")
 		}
+		switch outputFormat {
+		case "", "json", "table", output.CSV, output.TSV, output.NDJSON:
+		default:
+			return fmt.Errorf("invalid --output %q (want json, table, csv, tsv or ndjson)", outputFormat)
+		}
+		if outputFormat != "" && outputFormat != "json" && (jsonOutput || plainOutput || jqExpr != "") {
+			return fmt.Errorf("--output %s cannot be combined with --json, --plain or --jq", outputFormat)
+		}
+		if retries < 0 {
+			return fmt.Errorf("--retries must not be negative")
+		}
+		if timeout < 0 {
+			return fmt.Errorf("--timeout must not be negative")
+		}
+		switch dryRun {
+		case "", client.DryRunText, client.DryRunCurl, client.DryRunHAR:
+		default:
+			return fmt.Errorf("invalid --dry-run %q (want text, curl or har)", dryRun)
+		}
+		// Arguments and flags are valid; failures from here on are runtime
+		// errors where printing usage would only bury the message.
+		cmd.SilenceUsage = true
 		return nil
 	},
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	// Ctrl-C cancels the command context so in-flight requests abort and
+	// long-running commands can report what they finished.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if traceSpans != nil {
+		name := rootCmd.Name()
+		if cmd, _, ferr := rootCmd.Find(os.Args[1:]); ferr == nil {
+			name = cmd.CommandPath()
+		}
+		spanErr := err
+		if errors.Is(err, client.ErrDryRun) {
+			spanErr = nil
+		}
+		if serr := traceSpans.Close(name, spanErr); serr != nil {
+			fmt.Fprintf(os.Stderr, "warning: trace file: %v\n", serr)
+		}
+	}
+	if err != nil && !errors.Is(err, client.ErrDryRun) {
+		writeError(os.Stderr, err)
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&region, "region", "us", "API region: us or eu")
+	rootCmd.PersistentFlags().StringVar(&region, "region", "us", "API region: us or eu (overrides the profile)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "config profile to use (default $CIO_PROFILE or the current profile)")
+	rootCmd.PersistentFlags().StringVar(&tokenFile, "token-file", "", "read the App API token from a file (default $CUSTOMERIO_API_TOKEN_FILE)")
 	rootCmd.PersistentFlags().StringVar(&jqExpr, "jq", "", "jq expression to filter JSON output")
-	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "force JSON output")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "print JSON, including errors, even on a terminal (same as --output json)")
 	rootCmd.PersistentFlags().BoolVar(&plainOutput, "plain", false, "print compact/plain output")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", "", "output format: json, table, csv, tsv or ndjson (default table on a terminal, json otherwise)")
+	rootCmd.PersistentFlags().StringSliceVar(&columns, "columns", nil, "columns for table, csv, tsv and ndjson output, e.g. id,name,metrics.sent")
+	rootCmd.PersistentFlags().StringVar(&sortBy, "sort-by", "", "sort rows by a column, descending with a leading -")
+	rootCmd.PersistentFlags().BoolVar(&noHeaders, "no-headers", false, "omit the header row of table, csv and tsv output")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", client.DefaultMaxRetries, "retry failed requests up to N times (0 disables)")
+	rootCmd.PersistentFlags().DurationVar(&retryMaxWait, "retry-max-wait", client.DefaultRetryMaxWait, "maximum wait between retries")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", client.DefaultTimeout, "timeout for each HTTP request (0 disables)")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "log each HTTP request's method, URL, status, latency and rate-limit headers to stderr")
+	rootCmd.PersistentFlags().BoolVar(&traceHTTP, "trace", false, "like --verbose, plus redacted request and response headers and bodies")
+	rootCmd.PersistentFlags().StringVar(&traceFile, "trace-file", "", "append OpenTelemetry spans (OTLP/JSON) to a file (default $CIO_TRACE_FILE)")
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "save every request/response pair to a cassette directory, auth scrubbed (default $CIO_RECORD)")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "answer requests from a cassette directory without network access (default $CIO_REPLAY)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "neither read nor store cached GET responses")
+	rootCmd.PersistentFlags().BoolVar(&refreshCache, "refresh", false, "fetch fresh GET responses and update the cache")
+	rootCmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "refuse requests that could change data (also $CIO_READ_ONLY or the profile's read_only)")
+	rootCmd.PersistentFlags().StringVar(&dryRun, "dry-run", "", "print requests with side effects instead of sending them: text, curl or har")
+	rootCmd.PersistentFlags().Lookup("dry-run").NoOptDefVal = client.DryRunText
+	rootCmd.PersistentFlags().StringToStringVar(&rateLimits, "rate-limit", nil, "override client rate limits, e.g. general=5/s,broadcast=1/10s (off disables)")
+}
+
+// activeProfile resolves the profile selected by --profile, $CIO_PROFILE or
+// the config file, with an explicit --region or token file taking
+// precedence.
+func activeProfile() (*config.Profile, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	p, err := cfg.Active(profileName)
+	if err != nil {
+		return nil, err
+	}
+	if p.Region == "" || rootCmd.PersistentFlags().Changed("region") {
+		p.Region = region
+	}
+	if tokenFile != "" {
+		p.TokenFile = tokenFile
+	} else if f := os.Getenv("CUSTOMERIO_API_TOKEN_FILE"); f != "" {
+		p.TokenFile = f
+	}
+	return p, nil
 }
 
 var newClient = func() (*client.Client, error) {
-	return client.New(region)
+	p, err := activeProfile()
+	if err != nil {
+		return nil, err
+	}
+	c, err := client.New(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := configureClient(c, p); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-func printJSON(data json.RawMessage) error {
-	if plainOutput {
-		return output.PrintPlain(data)
+var newTrackClient = func() (*client.Client, error) {
+	p, err := activeProfile()
+	if err != nil {
+		return nil, err
 	}
-	return output.Print(data, jqExpr)
+	c, err := client.NewTrack(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := configureClient(c, p); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-func printObject(v any) error {
-	data, err := json.Marshal(v)
+var newPipelinesClient = func() (*client.Client, error) {
+	p, err := activeProfile()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return printJSON(data)
+	c, err := client.NewPipelines(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := configureClient(c, p); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-func readBody(cmd *cobra.Command) (json.RawMessage, error) {
-	body, _ := cmd.Flags().GetString("body")
-	if body != "" {
-		return json.RawMessage(body), nil
+// configureClient applies the retry, timeout, read-only, audit, cassette,
+// cache, tracing, dry-run and rate limit settings. --rate-limit takes
+// precedence over the profile's rate_limits.
+func configureClient(c *client.Client, p *config.Profile) error {
+	c.MaxRetries = retries
+	c.RetryMaxWait = retryMaxWait
+	c.Timeout = timeout
+	c.ReadOnly, _ = readOnlyMode(p)
+	audit, err := auditHook(p)
+	if err != nil {
+		return err
+	}
+	c.Audit = audit
+	if err := configureCassette(c); err != nil {
+		return err
+	}
+	if err := configureCache(c, p); err != nil {
+		return err
+	}
+	if err := configureTracing(c); err != nil {
+		return err
+	}
+	if dryRun != "" {
+		c.DryRun = &client.DryRun{Out: os.Stdout, Format: dryRun}
+	}
+
+	overrides := make(map[string]string, len(p.RateLimits)+len(rateLimits))
+	for class, v := range p.RateLimits {
+		overrides[class] = v
 	}
-	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) == 0 {
-		data, err := io.ReadAll(os.Stdin)
+	for class, v := range rateLimits {
+		overrides[class] = v
+	}
+	if len(overrides) > 0 {
+		limits, err := client.ParseRateLimits(overrides)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		if len(data) > 0 {
-			return json.RawMessage(data), nil
+		c.Limiter = client.NewLimiter(limits)
+	}
+	return nil
+}
+
+// configureCassette sends requests through a cassette for --record or
+// --replay. The replayer is loaded once so every client draws from the same
+// recorded sequence.
+func configureCassette(c *client.Client) error {
+	record, replay := cassetteDirs()
+	switch {
+	case record != "" && replay != "":
+		return fmt.Errorf("--record and --replay cannot be used together")
+	case replay != "":
+		if replayer == nil {
+			r, err := client.LoadCassette(replay)
+			if err != nil {
+				return err
+			}
+			replayer = r
 		}
+		c.WrapTransport(func(http.RoundTripper) http.RoundTripper { return replayer })
+	case record != "":
+		c.WrapTransport(func(base http.RoundTripper) http.RoundTripper {
+			return &client.Recorder{Base: base, Dir: record}
+		})
+	}
+	return nil
+}
+
+func cassetteDirs() (record, replay string) {
+	record, replay = recordDir, replayDir
+	if record == "" {
+		record = os.Getenv("CIO_RECORD")
+	}
+	if replay == "" {
+		replay = os.Getenv("CIO_REPLAY")
 	}
-	return nil, nil
+	return record, replay
 }
 
-func requireBody(cmd *cobra.Command) (json.RawMessage, error) {
-	body, err := readBody(cmd)
+// configureCache turns on the response cache when $CIO_CACHE or the
+// profile's cache asks for it. It stays off with --no-cache and while
+// recording or replaying, where every request must reach the cassette.
+func configureCache(c *client.Client, p *config.Profile) error {
+	if noCache || !cacheEnabled(p) {
+		return nil
+	}
+	if record, replay := cassetteDirs(); record != "" || replay != "" {
+		return nil
+	}
+	ttls, err := client.ParseCacheTTLs(p.CacheTTLs)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	dir, err := client.DefaultCacheDir()
+	if err != nil {
+		return err
+	}
+	c.Cache = &client.Cache{Dir: dir, Scope: p.Name + "/" + p.Region, TTLs: ttls, Refresh: refreshCache}
+	return nil
+}
+
+func cacheEnabled(p *config.Profile) bool {
+	if v, err := strconv.ParseBool(os.Getenv("CIO_CACHE")); err == nil {
+		return v
+	}
+	return p.Cache
+}
+
+// configureTracing installs a client.Tracer for --verbose, --trace and
+// --trace-file. The span file is opened once and shared by every client so
+// all requests land in one trace.
+func configureTracing(c *client.Client) error {
+	path := traceFile
+	if path == "" {
+		path = os.Getenv("CIO_TRACE_FILE")
+	}
+	if !verbose && !traceHTTP && path == "" {
+		return nil
+	}
+	if path != "" && traceSpans == nil {
+		spans, err := client.OpenSpanFile(path)
+		if err != nil {
+			return fmt.Errorf("trace file: %w", err)
+		}
+		traceSpans = spans
 	}
-	if body == nil {
-		return nil, fmt.Errorf("request body is required (use -body flag or pipe via stdin)")
+	t := &client.Tracer{Bodies: traceHTTP, Spans: traceSpans}
+	if verbose || traceHTTP {
+		t.Log = os.Stderr
 	}
-	return body, nil
+	c.Trace(t)
+	return nil
 }
 
-func addBodyFlag(cmd *cobra.Command) {
-	cmd.Flags().String("body", "", "JSON request body")
+// readOnlyMode reports whether read-only mode is on and what turned it on.
+// Each source can only enable it, so a profile or environment meant for
+// support staff cannot be overridden with a flag.
+func readOnlyMode(p *config.Profile) (bool, string) {
+	if readOnly {
+		return true, "flag"
+	}
+	if v, err := strconv.ParseBool(os.Getenv("CIO_READ_ONLY")); err == nil && v {
+		return true, "env (CIO_READ_ONLY)"
+	}
+	if p.ReadOnly {
+		return true, fmt.Sprintf("profile %s", p.Name)
+	}
+	return false, ""
+}
+
+// stdoutIsTerminal reports whether output goes to a person rather than a
+// pipe or file.
+var stdoutIsTerminal = func() bool {
+	stat, err := os.Stdout.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// wantJSON reports whether JSON was asked for with --json or --output json,
+// which also switches errors and text-only commands to JSON.
+func wantJSON() bool {
+	return jsonOutput || outputFormat == "json"
+}
+
+// tableOutput reports whether responses are printed as tables: when asked
+// for, or on a terminal unless a JSON mode was picked.
+func tableOutput() bool {
+	if outputFormat != "" {
+		return outputFormat == "table"
+	}
+	return !wantJSON() && !plainOutput && jqExpr == "" && stdoutIsTerminal()
+}
+
+// terminalWidth is the width tables are truncated to; 0 leaves them whole.
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	if !stdoutIsTerminal() {
+		return 0
+	}
+	return ttyWidth(os.Stdout)
+}
+
+// recordOutput reports whether responses are written as rows, one call per
+// page, by --output csv, tsv or ndjson.
+func recordOutput() bool {
+	switch outputFormat {
+	case output.CSV, output.TSV, output.NDJSON:
+		return true
+	}
+	return false
+}
+
+func printJSON(data json.RawMessage) error {
+	if recordOutput() {
+		// One writer per run keeps the header and columns of the first page.
+		if records == nil {
+			rw, err := output.NewRecordWriter(os.Stdout, outputFormat, output.TableOptions{
+				Columns:   columns,
+				SortBy:    sortBy,
+				NoHeaders: noHeaders,
+			})
+			if err != nil {
+				return err
+			}
+			rw.Warnings = os.Stderr
+			records = rw
+		}
+		return records.Write(data)
+	}
+	if tableOutput() {
+		return output.PrintTable(os.Stdout, data, output.TableOptions{
+			Columns:   columns,
+			SortBy:    sortBy,
+			NoHeaders: noHeaders,
+			Width:     terminalWidth(),
+		})
+	}
+	if plainOutput {
+		return output.PrintPlain(data)
+	}
+	return output.Print(data, jqExpr)
+}
+
+func printObject(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return printJSON(data)
 }
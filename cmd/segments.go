@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -21,7 +23,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/segments", nil)
+			data, err := c.Get(cmd.Context(), "/v1/segments", nil)
 			if err != nil {
 				return err
 			}
@@ -38,7 +40,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/segments/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/segments/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -58,7 +60,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Post("/v1/segments", body)
+			data, err := c.Post(cmd.Context(), "/v1/segments", body)
 			if err != nil {
 				return err
 			}
@@ -67,7 +69,7 @@ func init() {
 	}
 	addBodyFlag(create)
 
-	rm := &cobra.Command{
+	rm := destructive(&cobra.Command{
 		Use:     "rm <id>",
 		Aliases: []string{"delete"},
 		Short:   "Delete a segment",
@@ -77,13 +79,31 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Delete(fmt.Sprintf("/v1/segments/%s", args[0]), nil)
+			err = confirm(cmd, c, func(ctx context.Context) (string, error) {
+				what, err := describeResource(ctx, c, "Delete", "segment", args[0], fmt.Sprintf("/v1/segments/%s", args[0]))
+				if err != nil {
+					return "", err
+				}
+				data, err := c.Get(ctx, fmt.Sprintf("/v1/segments/%s/customer_count", args[0]), nil)
+				if err != nil {
+					return "", err
+				}
+				var count struct{ Count *int64 }
+				if json.Unmarshal(data, &count) == nil && count.Count != nil {
+					what += fmt.Sprintf(" (%d members)", *count.Count)
+				}
+				return what, nil
+			})
+			if err != nil {
+				return err
+			}
+			data, err := c.Delete(cmd.Context(), fmt.Sprintf("/v1/segments/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
 			return printJSON(data)
 		},
-	}
+	})
 
 	count := &cobra.Command{
 		Use:   "count <id>",
@@ -94,7 +114,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/segments/%s/customer_count", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/segments/%s/customer_count", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -111,13 +131,10 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/segments/%s/membership", args[0]), nil)
-			if err != nil {
-				return err
-			}
-			return printJSON(data)
+			return listPages(cmd, c, fmt.Sprintf("/v1/segments/%s/membership", args[0]), nil)
 		},
 	}
+	addPageFlags(members)
 
 	deps := &cobra.Command{
 		Use:   "deps <id>",
@@ -128,7 +145,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/segments/%s/dependencies", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/segments/%s/dependencies", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -22,7 +22,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Post("/v1/send/email", body)
+			data, err := c.Post(cmd.Context(), "/v1/send/email", body)
 			if err != nil {
 				return err
 			}
@@ -43,7 +43,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Post("/v1/send/push", body)
+			data, err := c.Post(cmd.Context(), "/v1/send/push", body)
 			if err != nil {
 				return err
 			}
@@ -64,7 +64,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Post("/v1/send/sms", body)
+			data, err := c.Post(cmd.Context(), "/v1/send/sms", body)
 			if err != nil {
 				return err
 			}
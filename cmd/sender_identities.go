@@ -21,7 +21,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/sender_identities", nil)
+			data, err := c.Get(cmd.Context(), "/v1/sender_identities", nil)
 			if err != nil {
 				return err
 			}
@@ -38,7 +38,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/sender_identities/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/sender_identities/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -55,7 +55,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/sender_identities/%s/used_by", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/sender_identities/%s/used_by", args[0]), nil)
 			if err != nil {
 				return err
 			}
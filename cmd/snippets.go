@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -21,7 +23,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/snippets", nil)
+			data, err := c.Get(cmd.Context(), "/v1/snippets", nil)
 			if err != nil {
 				return err
 			}
@@ -41,7 +43,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Put("/v1/snippets", body)
+			data, err := c.Put(cmd.Context(), "/v1/snippets", body)
 			if err != nil {
 				return err
 			}
@@ -50,7 +52,7 @@ func init() {
 	}
 	addBodyFlag(upsert)
 
-	rm := &cobra.Command{
+	rm := destructive(&cobra.Command{
 		Use:     "rm <name>",
 		Aliases: []string{"delete"},
 		Short:   "Delete a snippet",
@@ -60,13 +62,34 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Delete(fmt.Sprintf("/v1/snippets/%s", args[0]), nil)
+			err = confirm(cmd, c, func(ctx context.Context) (string, error) {
+				data, err := c.Get(ctx, "/v1/snippets", nil)
+				if err != nil {
+					return "", err
+				}
+				var list struct {
+					Snippets []struct{ Name string }
+				}
+				if err := json.Unmarshal(data, &list); err != nil {
+					return "", err
+				}
+				for _, s := range list.Snippets {
+					if s.Name == args[0] {
+						return fmt.Sprintf("Delete snippet %q", args[0]), nil
+					}
+				}
+				return "", fmt.Errorf("snippet %q not found", args[0])
+			})
+			if err != nil {
+				return err
+			}
+			data, err := c.Delete(cmd.Context(), fmt.Sprintf("/v1/snippets/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
 			return printJSON(data)
 		},
-	}
+	})
 
 	parent.AddCommand(ls, upsert, rm)
 	rootCmd.AddCommand(parent)
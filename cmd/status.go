@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/leechael/cio/internal/auth"
 	"github.com/spf13/cobra"
 )
 
@@ -12,47 +14,74 @@ func init() {
 		Use:   "status",
 		Short: "Check API token and connectivity",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			token := os.Getenv("CUSTOMERIO_API_TOKEN")
-			if token == "" {
-				fmt.Fprintln(os.Stderr, "CUSTOMERIO_API_TOKEN is not set.")
-				fmt.Fprintln(os.Stderr, "")
-				fmt.Fprintln(os.Stderr, "Set it with:")
-				fmt.Fprintln(os.Stderr, "  export CUSTOMERIO_API_TOKEN=\"your-app-api-key\"")
-				fmt.Fprintln(os.Stderr, "")
-				fmt.Fprintln(os.Stderr, "Or use 1Password CLI:")
-				fmt.Fprintln(os.Stderr, "  op run --env-file=.env -- cio status")
-				return fmt.Errorf("missing CUSTOMERIO_API_TOKEN")
+			p, err := activeProfile()
+			if err != nil {
+				return err
 			}
 
 			c, err := newClient()
 			if err != nil {
-				return err
+				var noToken *auth.NoTokenError
+				if !errors.As(err, &noToken) {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "%s is not set.\n", noToken.TokenEnv)
+				fmt.Fprintln(os.Stderr, "")
+				fmt.Fprintln(os.Stderr, "Set it with:")
+				fmt.Fprintf(os.Stderr, "  export %s=\"your-app-api-key\"\n", noToken.TokenEnv)
+				fmt.Fprintln(os.Stderr, "")
+				fmt.Fprintln(os.Stderr, "Or store it in the OS keyring:")
+				fmt.Fprintln(os.Stderr, "  cio auth login")
+				fmt.Fprintln(os.Stderr, "")
+				fmt.Fprintln(os.Stderr, "Or fetch it on demand from 1Password CLI:")
+				fmt.Fprintln(os.Stderr, "  cio config set token_command \"op read op://vault-name/Customer.io/api-token\"")
+				return fmt.Errorf("missing %s", noToken.TokenEnv)
 			}
 
-			_, err = c.Get("/v1/info/ip_addresses", nil)
+			_, err = c.Get(cmd.Context(), "/v1/info/ip_addresses", nil)
 			if err != nil {
+				if exitCode(err) != exitAuth {
+					return err
+				}
 				fmt.Fprintln(os.Stderr, "Authentication failed.")
 				fmt.Fprintln(os.Stderr, "")
-				fmt.Fprintln(os.Stderr, "Your CUSTOMERIO_API_TOKEN may be invalid or expired.")
+				fmt.Fprintf(os.Stderr, "The token from %s may be invalid or expired.\n", c.TokenSource)
 				fmt.Fprintln(os.Stderr, "Get a new key from: https://fly.customer.io/settings/api_credentials")
-				return fmt.Errorf("authentication failed")
+				return fmt.Errorf("authentication failed: %w", err)
 			}
 
+			token := c.Token
 			masked := "***"
 			if len(token) >= 8 {
 				masked = token[:4] + "..." + token[len(token)-4:]
 			}
 
-			if jsonOutput {
-				return printObject(map[string]any{
+			ro, roSource := readOnlyMode(p)
+
+			if wantJSON() {
+				status := map[string]any{
 					"authenticated": true,
-					"region":        region,
+					"profile":       p.Name,
+					"region":        p.Region,
 					"token":         masked,
-				})
+					"token_source":  c.TokenSource,
+					"read_only":     ro,
+				}
+				if ro {
+					status["read_only_source"] = roSource
+				}
+				return printObject(status)
 			}
 
 			fmt.Printf("Authenticated (%s)\n", masked)
-			fmt.Printf("Region: %s\n", region)
+			fmt.Printf("Profile: %s\n", p.Name)
+			fmt.Printf("Region: %s\n", p.Region)
+			fmt.Printf("Token source: %s\n", c.TokenSource)
+			if ro {
+				fmt.Printf("Read-only: yes (%s)\n", roSource)
+			} else {
+				fmt.Println("Read-only: no")
+			}
 			return nil
 		},
 	})
@@ -19,7 +19,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/subscription_topics", nil)
+			data, err := c.Get(cmd.Context(), "/v1/subscription_topics", nil)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package cmd
+
+import "os"
+
+// ttyWidth is unknown here; tables are only truncated when $COLUMNS is set.
+func ttyWidth(*os.File) int { return 0 }
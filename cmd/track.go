@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/leechael/cio/internal/client"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	parent := &cobra.Command{
+		Use:   "track",
+		Short: "Write people and objects via the Track API",
+		Long: "Write people and objects via the Track API (/api/v2/entity).\n" +
+			"Authenticates with $CUSTOMERIO_SITE_ID and $CUSTOMERIO_API_KEY, or the\n" +
+			"profile's site_id, track_key_env and track_key_command.",
+	}
+
+	identify := &cobra.Command{
+		Use:   "identify <id>",
+		Short: "Create or update a person or object",
+		Long: "Create or update a person, or an object with --object-type.\n" +
+			"Attributes are read from --body or stdin as a JSON object.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := trackEntity(cmd, "identify", args[0])
+			if err != nil {
+				return err
+			}
+			if e.Attributes, err = trackAttributes(cmd); err != nil {
+				return err
+			}
+			return sendEntity(cmd, e)
+		},
+	}
+	addIdentifierFlags(identify, true)
+	addBodyFlag(identify)
+
+	event := &cobra.Command{
+		Use:   "event <id> <name>",
+		Short: "Send an event for a person",
+		Long:  "Send an event for a person. Event data is read from --body or stdin as a JSON object.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := trackEntity(cmd, "event", args[0])
+			if err != nil {
+				return err
+			}
+			e.Name = args[1]
+			e.ID, _ = cmd.Flags().GetString("event-id")
+			e.Timestamp, _ = cmd.Flags().GetInt64("timestamp")
+			if e.Attributes, err = trackAttributes(cmd); err != nil {
+				return err
+			}
+			return sendEntity(cmd, e)
+		},
+	}
+	addIdentifierFlags(event, false)
+	addBodyFlag(event)
+	event.Flags().String("event-id", "", "Unique event ID (ULID) for deduplication")
+	event.Flags().Int64("timestamp", 0, "Event time as a Unix timestamp (default now)")
+
+	del := &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete a person or object",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := trackEntity(cmd, "delete", args[0])
+			if err != nil {
+				return err
+			}
+			return sendEntity(cmd, e)
+		},
+	}
+	addIdentifierFlags(del, true)
+
+	suppress := &cobra.Command{
+		Use:   "suppress <id>",
+		Short: "Delete a person and suppress their identifier",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := trackEntity(cmd, "suppress", args[0])
+			if err != nil {
+				return err
+			}
+			return sendEntity(cmd, e)
+		},
+	}
+	addIdentifierFlags(suppress, false)
+
+	unsuppress := &cobra.Command{
+		Use:   "unsuppress <id>",
+		Short: "Allow a suppressed person to be identified again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := trackEntity(cmd, "unsuppress", args[0])
+			if err != nil {
+				return err
+			}
+			return sendEntity(cmd, e)
+		},
+	}
+	addIdentifierFlags(unsuppress, false)
+
+	parent.AddCommand(identify, event, del, suppress, unsuppress, trackBatchCmd())
+	rootCmd.AddCommand(parent)
+}
+
+func addIdentifierFlags(cmd *cobra.Command, objects bool) {
+	cmd.Flags().String("by", "id", "Identifier the argument holds: id, email or cio_id")
+	if objects {
+		cmd.Flags().String("object-type", "", "Object type ID; the argument is then an object ID")
+	}
+}
+
+// trackEntity builds the entity an action applies to from the identifier
+// argument and the --by / --object-type flags.
+func trackEntity(cmd *cobra.Command, action, id string) (*client.Entity, error) {
+	if id == "" {
+		return nil, fmt.Errorf("identifier must not be empty")
+	}
+	if objectType, _ := cmd.Flags().GetString("object-type"); objectType != "" {
+		if cmd.Flags().Changed("by") {
+			return nil, fmt.Errorf("--by cannot be used with --object-type")
+		}
+		return &client.Entity{
+			Type:        "object",
+			Action:      action,
+			Identifiers: map[string]string{"object_type_id": objectType, "object_id": id},
+		}, nil
+	}
+	by, _ := cmd.Flags().GetString("by")
+	switch by {
+	case "id", "email", "cio_id":
+	default:
+		return nil, fmt.Errorf("invalid --by %q (want id, email or cio_id)", by)
+	}
+	return &client.Entity{
+		Type:        "person",
+		Action:      action,
+		Identifiers: map[string]string{by: id},
+	}, nil
+}
+
+// trackAttributes reads the optional attribute object from --body or stdin.
+func trackAttributes(cmd *cobra.Command) (json.RawMessage, error) {
+	body, err := readBody(cmd)
+	if err != nil || body == nil {
+		return nil, err
+	}
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(body, &attrs); err != nil || attrs == nil {
+		return nil, fmt.Errorf("attributes must be a JSON object")
+	}
+	return bytes.TrimSpace(body), nil
+}
+
+func sendEntity(cmd *cobra.Command, e *client.Entity) error {
+	c, err := newTrackClient()
+	if err != nil {
+		return err
+	}
+	data, err := c.Post(cmd.Context(), "/api/v2/entity", e)
+	if err != nil {
+		return err
+	}
+	return printJSON(data)
+}
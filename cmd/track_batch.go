@@ -0,0 +1,600 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/leechael/cio/internal/client"
+	"github.com/spf13/cobra"
+)
+
+func trackBatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "batch --file <path>",
+		Short: "Send operations from an NDJSON or CSV file via /api/v2/batch",
+		Long: `Send operations from an NDJSON or CSV file via /api/v2/batch.
+
+NDJSON lines are /api/v2/entity operations. CSV rows are turned into
+operations by mapping columns to entity fields with --map column=target,
+where target is type, action, name, timestamp, id (event ID),
+identifiers.<key>, attributes.<key> or - (ignore). Unmapped columns named
+id, cio_id, object_id or object_type_id become identifiers; type, action,
+name and timestamp map to themselves; anything else becomes an attribute.
+
+Operations are packed into requests under the 500 KB batch limit and sent
+concurrently, subject to the "track" rate limit. Each input line gets a
+result in the report (default <file>.report.ndjson). Progress is saved to
+a checkpoint so an interrupted run can continue with --resume.`,
+		Args: cobra.NoArgs,
+		RunE: runTrackBatch,
+	}
+	cmd.Flags().String("file", "", "Input file, or - for stdin")
+	cmd.Flags().String("format", "", "Input format: ndjson or csv (default from the file extension)")
+	cmd.Flags().StringArray("map", nil, "Map a CSV column to an entity field, e.g. email=identifiers.email")
+	cmd.Flags().String("type", "person", "Entity type for CSV rows without a type column")
+	cmd.Flags().String("action", "identify", "Action for CSV rows without an action column")
+	cmd.Flags().Int("concurrency", 4, "Number of batch requests in flight")
+	cmd.Flags().String("report", "", "Per-line result file, - for stdout (default <file>.report.ndjson)")
+	cmd.Flags().String("checkpoint", "", "Checkpoint file (default <file>.checkpoint)")
+	cmd.Flags().Bool("resume", false, "Skip operations recorded as sent in the checkpoint")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+// batchOp is one input line. err is set when the line could not be turned
+// into an operation or Customer.io rejected it.
+type batchOp struct {
+	line int
+	data json.RawMessage
+	err  error
+}
+
+// batchChunk is a group of consecutive operations sent in one request.
+// err is set when the request's outcome is unknown.
+type batchChunk struct {
+	seq         int
+	first, last int
+	ops         []batchOp
+	size        int
+	err         error
+}
+
+// batchCheckpoint records which input lines have been sent: every line up
+// to Line, plus the line ranges of chunks that finished out of order.
+type batchCheckpoint struct {
+	Input string   `json:"input"`
+	Line  int      `json:"line"`
+	Done  [][2]int `json:"done,omitempty"`
+}
+
+func (cp *batchCheckpoint) sent(line int) bool {
+	if line <= cp.Line {
+		return true
+	}
+	for _, r := range cp.Done {
+		if line >= r[0] && line <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func runTrackBatch(cmd *cobra.Command, args []string) error {
+	file, _ := cmd.Flags().GetString("file")
+	format, _ := cmd.Flags().GetString("format")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	reportPath, _ := cmd.Flags().GetString("report")
+	cpPath, _ := cmd.Flags().GetString("checkpoint")
+	resume, _ := cmd.Flags().GetBool("resume")
+
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if format == "" {
+		format = "ndjson"
+		if strings.EqualFold(filepath.Ext(file), ".csv") {
+			format = "csv"
+		}
+	}
+	if format != "ndjson" && format != "csv" {
+		return fmt.Errorf("invalid --format %q (want ndjson or csv)", format)
+	}
+	if file != "-" {
+		if reportPath == "" {
+			reportPath = file + ".report.ndjson"
+		}
+		if cpPath == "" {
+			cpPath = file + ".checkpoint"
+		}
+	} else if reportPath == "" {
+		reportPath = "-"
+	}
+	if resume && cpPath == "" {
+		return fmt.Errorf("--resume needs --checkpoint when reading stdin")
+	}
+
+	cp := &batchCheckpoint{Input: file}
+	if resume {
+		var err error
+		if cp, err = loadCheckpoint(cpPath, file); err != nil {
+			return err
+		}
+	}
+
+	in := os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var ops iter.Seq2[batchOp, error]
+	if format == "csv" {
+		m, err := newCSVMapping(cmd)
+		if err != nil {
+			return err
+		}
+		ops = m.ops(in)
+	} else {
+		ops = ndjsonOps(in)
+	}
+
+	c, err := newTrackClient()
+	if err != nil {
+		return err
+	}
+	// A dry run sends nothing, so it writes no report and leaves any
+	// checkpoint as it was.
+	dry := c.DryRun != nil
+	if dry {
+		cpPath = ""
+	}
+
+	var report io.Writer = os.Stdout
+	if dry {
+		report = io.Discard
+	} else if reportPath != "-" {
+		flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if resume {
+			flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		f, err := os.OpenFile(reportPath, flag, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		report = f
+	}
+	w := bufio.NewWriter(report)
+	defer w.Flush()
+	enc := json.NewEncoder(w)
+
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	jobs := make(chan *batchChunk)
+	// Buffered so workers never block on a collector that has given up.
+	results := make(chan *batchChunk, concurrency)
+	var (
+		readErr error
+		skipped int
+		wg      sync.WaitGroup
+	)
+	// The reader skips against the checkpoint as loaded; the collector
+	// updates cp while it runs.
+	loaded := *cp
+	go func() {
+		defer close(jobs)
+		skipped, readErr = chunkOps(ctx, ops, &loaded, jobs)
+	}()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ch := range jobs {
+				ch.send(ctx, c)
+				results <- ch
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		sent, failed int
+		fatal        error
+		pending      = make(map[int]*batchChunk)
+		next         int
+		prior        = cp.Done
+	)
+	for ch := range results {
+		if ch.err != nil {
+			if fatal == nil {
+				fatal = ch.err
+				cancel()
+			}
+			continue
+		}
+		for _, op := range ch.ops {
+			res := map[string]any{"line": op.line, "status": "ok"}
+			if op.err != nil {
+				res["status"] = "failed"
+				res["error"] = op.err.Error()
+				failed++
+			} else {
+				sent++
+			}
+			if err := enc.Encode(res); err != nil {
+				return err
+			}
+		}
+
+		pending[ch.seq] = ch
+		for pending[next] != nil {
+			cp.Line = max(cp.Line, pending[next].last)
+			delete(pending, next)
+			next++
+		}
+		cp.Done = nil
+		for _, r := range prior {
+			if r[1] > cp.Line {
+				cp.Done = append(cp.Done, r)
+			}
+		}
+		for _, p := range pending {
+			cp.Done = append(cp.Done, [2]int{p.first, p.last})
+		}
+		slices.SortFunc(cp.Done, func(a, b [2]int) int { return a[0] - b[0] })
+		if cpPath != "" {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if err := saveCheckpoint(cpPath, cp); err != nil {
+				return err
+			}
+		}
+	}
+	if fatal == nil && readErr != nil && !errors.Is(readErr, context.Canceled) {
+		fatal = readErr
+	}
+	if fatal == nil && ctx.Err() != nil {
+		fatal = ctx.Err()
+	}
+
+	verb := "accepted"
+	if dry {
+		verb = "printed"
+	}
+	summary := fmt.Sprintf("%d operation(s) %s, %d failed", sent, verb, failed)
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped (already sent)", skipped)
+	}
+	if !dry && reportPath != "-" {
+		summary += "; report: " + reportPath
+	}
+	fmt.Fprintln(os.Stderr, summary)
+
+	if fatal != nil {
+		if cpPath == "" {
+			return fmt.Errorf("batch stopped: %w", fatal)
+		}
+		// Chunks with an unknown outcome are not in the checkpoint, so
+		// --resume sends them again.
+		if err := saveCheckpoint(cpPath, cp); err != nil {
+			return err
+		}
+		return fmt.Errorf("batch stopped, continue with --resume: %w", fatal)
+	}
+	if cpPath != "" {
+		if err := os.Remove(cpPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d operation(s) failed", failed)
+	}
+	if dry {
+		return client.ErrDryRun
+	}
+	return nil
+}
+
+// chunkOps packs operations into chunks under the batch size limit,
+// skipping lines the checkpoint records as sent. Oversized operations are
+// failed locally rather than sent.
+func chunkOps(ctx context.Context, ops iter.Seq2[batchOp, error], cp *batchCheckpoint, jobs chan<- *batchChunk) (int, error) {
+	// Room for {"batch":[ ... ]}.
+	const overhead = len(`{"batch":[]}`)
+
+	var (
+		ch      *batchChunk
+		seq     int
+		skipped int
+	)
+	flush := func() bool {
+		if ch == nil {
+			return true
+		}
+		select {
+		case jobs <- ch:
+			ch = nil
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for op, err := range ops {
+		if err != nil {
+			return skipped, err
+		}
+		if cp.sent(op.line) {
+			skipped++
+			continue
+		}
+		if op.err == nil && len(op.data) > client.MaxOperationBytes {
+			op.err = fmt.Errorf("operation is %d bytes, over the %d byte limit", len(op.data), client.MaxOperationBytes)
+		}
+		size := len(op.data) + 1
+		if op.err != nil {
+			size = 0
+		}
+		if ch != nil && ch.size+size > client.MaxBatchBytes {
+			if !flush() {
+				return skipped, ctx.Err()
+			}
+		}
+		if ch == nil {
+			ch = &batchChunk{seq: seq, first: op.line, size: overhead}
+			seq++
+		}
+		ch.ops = append(ch.ops, op)
+		ch.last = op.line
+		ch.size += size
+	}
+	if !flush() {
+		return skipped, ctx.Err()
+	}
+	return skipped, nil
+}
+
+func (ch *batchChunk) send(ctx context.Context, c *client.Client) {
+	var (
+		payload []json.RawMessage
+		index   []int
+	)
+	for i, op := range ch.ops {
+		if op.err == nil {
+			payload = append(payload, op.data)
+			index = append(index, i)
+		}
+	}
+	if len(payload) == 0 {
+		return
+	}
+	errs, err := c.TrackBatch(ctx, payload)
+	if errors.Is(err, client.ErrDryRun) {
+		return
+	}
+	if err != nil {
+		ch.err = err
+		return
+	}
+	for j, e := range errs {
+		ch.ops[index[j]].err = e
+	}
+}
+
+func loadCheckpoint(path, input string) (*batchCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+	cp := &batchCheckpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("parse checkpoint %s: %w", path, err)
+	}
+	if cp.Input != input {
+		return nil, fmt.Errorf("checkpoint %s is for %s, not %s", path, cp.Input, input)
+	}
+	return cp, nil
+}
+
+// saveCheckpoint replaces the checkpoint atomically so a crash mid-write
+// never leaves a truncated file behind.
+func saveCheckpoint(path string, cp *batchCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ndjsonOps yields one operation per non-blank line. Lines that are not
+// JSON objects become failed operations; read errors end the sequence.
+func ndjsonOps(r io.Reader) iter.Seq2[batchOp, error] {
+	return func(yield func(batchOp, error) bool) {
+		br := bufio.NewReader(r)
+		for line := 1; ; line++ {
+			text, err := br.ReadBytes('\n')
+			if len(bytes.TrimSpace(text)) > 0 {
+				op := batchOp{line: line}
+				var obj map[string]json.RawMessage
+				var buf bytes.Buffer
+				if jerr := json.Unmarshal(text, &obj); jerr != nil || obj == nil {
+					op.err = fmt.Errorf("not a JSON object")
+				} else if jerr := json.Compact(&buf, text); jerr != nil {
+					op.err = jerr
+				} else {
+					op.data = buf.Bytes()
+				}
+				if !yield(op, nil) {
+					return
+				}
+			}
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(batchOp{}, err)
+				return
+			}
+		}
+	}
+}
+
+// csvMapping turns CSV rows into entity operations.
+type csvMapping struct {
+	overrides map[string]string
+	typ       string
+	action    string
+}
+
+func newCSVMapping(cmd *cobra.Command) (*csvMapping, error) {
+	m := &csvMapping{overrides: make(map[string]string)}
+	m.typ, _ = cmd.Flags().GetString("type")
+	m.action, _ = cmd.Flags().GetString("action")
+	maps, _ := cmd.Flags().GetStringArray("map")
+	for _, spec := range maps {
+		column, target, ok := strings.Cut(spec, "=")
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid --map %q (want column=target)", spec)
+		}
+		if !validCSVTarget(target) {
+			return nil, fmt.Errorf("invalid --map target %q (want type, action, name, timestamp, id, identifiers.<key>, attributes.<key> or -)", target)
+		}
+		m.overrides[column] = target
+	}
+	return m, nil
+}
+
+func validCSVTarget(target string) bool {
+	switch target {
+	case "type", "action", "name", "timestamp", "id", "-":
+		return true
+	}
+	for _, prefix := range []string{"identifiers.", "attributes."} {
+		if key, ok := strings.CutPrefix(target, prefix); ok && key != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *csvMapping) target(column string) string {
+	if t, ok := m.overrides[column]; ok {
+		return t
+	}
+	switch column {
+	case "id", "cio_id", "object_id", "object_type_id":
+		return "identifiers." + column
+	case "type", "action", "name", "timestamp":
+		return column
+	}
+	return "attributes." + column
+}
+
+func (m *csvMapping) ops(r io.Reader) iter.Seq2[batchOp, error] {
+	return func(yield func(batchOp, error) bool) {
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("CSV input is empty")
+			}
+			yield(batchOp{}, err)
+			return
+		}
+		for column := range m.overrides {
+			if !slices.Contains(header, column) {
+				yield(batchOp{}, fmt.Errorf("--map column %q is not in the CSV header", column))
+				return
+			}
+		}
+		targets := make([]string, len(header))
+		for i, column := range header {
+			targets[i] = m.target(column)
+		}
+
+		for {
+			record, err := cr.Read()
+			if err == io.EOF {
+				return
+			}
+			var op batchOp
+			if err != nil {
+				var perr *csv.ParseError
+				if !errors.As(err, &perr) {
+					yield(batchOp{}, err)
+					return
+				}
+				op = batchOp{line: perr.StartLine, err: perr.Err}
+			} else {
+				line, _ := cr.FieldPos(0)
+				op = m.op(line, targets, record)
+			}
+			if !yield(op, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *csvMapping) op(line int, targets, record []string) batchOp {
+	op := batchOp{line: line}
+	entity := map[string]any{"type": m.typ, "action": m.action}
+	identifiers := make(map[string]string)
+	attributes := make(map[string]string)
+	for i, value := range record {
+		if value == "" {
+			continue
+		}
+		switch t := targets[i]; {
+		case t == "-":
+		case t == "timestamp":
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				op.err = fmt.Errorf("timestamp %q is not a Unix timestamp", value)
+				return op
+			}
+			entity["timestamp"] = ts
+		case strings.HasPrefix(t, "identifiers."):
+			identifiers[strings.TrimPrefix(t, "identifiers.")] = value
+		case strings.HasPrefix(t, "attributes."):
+			attributes[strings.TrimPrefix(t, "attributes.")] = value
+		default:
+			entity[t] = value
+		}
+	}
+	if len(identifiers) == 0 {
+		op.err = fmt.Errorf("row has no identifiers")
+		return op
+	}
+	entity["identifiers"] = identifiers
+	if len(attributes) > 0 {
+		entity["attributes"] = attributes
+	}
+	op.data, op.err = json.Marshal(entity)
+	return op
+}
@@ -21,7 +21,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/transactional", nil)
+			data, err := c.Get(cmd.Context(), "/v1/transactional", nil)
 			if err != nil {
 				return err
 			}
@@ -38,7 +38,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/transactional/%s", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/transactional/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -55,7 +55,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/transactional/%s/metrics", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/transactional/%s/metrics", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -72,7 +72,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/transactional/%s/metrics/links", args[0]), nil)
+			data, err := c.Get(cmd.Context(), fmt.Sprintf("/v1/transactional/%s/metrics/links", args[0]), nil)
 			if err != nil {
 				return err
 			}
@@ -95,13 +95,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
@@ -125,13 +125,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
@@ -149,13 +149,10 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get(fmt.Sprintf("/v1/transactional/%s/deliveries", args[0]), nil)
-			if err != nil {
-				return err
-			}
-			return printJSON(data)
+			return listPages(cmd, c, fmt.Sprintf("/v1/transactional/%s/deliveries", args[0]), nil)
 		},
 	}
+	addPageFlags(deliveries)
 
 	parent.AddCommand(ls, get, txMetrics, txLinkMetrics, txContent, txTranslation, deliveries)
 	rootCmd.AddCommand(parent)
@@ -17,7 +17,7 @@ func init() {
 		Use:   "version",
 		Short: "Print the version of cio",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if jsonOutput {
+			if wantJSON() {
 				return printObject(map[string]string{
 					"cli":     "cio",
 					"version": version,
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -21,7 +22,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Get("/v1/reporting_webhooks", nil)
+			data, err := c.Get(cmd.Context(), "/v1/reporting_webhooks", nil)
 			if err != nil {
 				return err
 			}
@@ -44,13 +45,13 @@ func init() {
 				return err
 			}
 			if body != nil {
-				data, err := c.Put(path, body)
+				data, err := c.Put(cmd.Context(), path, body)
 				if err != nil {
 					return err
 				}
 				return printJSON(data)
 			}
-			data, err := c.Get(path, nil)
+			data, err := c.Get(cmd.Context(), path, nil)
 			if err != nil {
 				return err
 			}
@@ -71,7 +72,7 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Post("/v1/reporting_webhooks", body)
+			data, err := c.Post(cmd.Context(), "/v1/reporting_webhooks", body)
 			if err != nil {
 				return err
 			}
@@ -80,7 +81,7 @@ func init() {
 	}
 	addBodyFlag(create)
 
-	rm := &cobra.Command{
+	rm := destructive(&cobra.Command{
 		Use:     "rm <id>",
 		Aliases: []string{"delete"},
 		Short:   "Delete a reporting webhook",
@@ -90,13 +91,19 @@ func init() {
 			if err != nil {
 				return err
 			}
-			data, err := c.Delete(fmt.Sprintf("/v1/reporting_webhooks/%s", args[0]), nil)
+			err = confirm(cmd, c, func(ctx context.Context) (string, error) {
+				return describeResource(ctx, c, "Delete", "reporting webhook", args[0], fmt.Sprintf("/v1/reporting_webhooks/%s", args[0]))
+			})
+			if err != nil {
+				return err
+			}
+			data, err := c.Delete(cmd.Context(), fmt.Sprintf("/v1/reporting_webhooks/%s", args[0]), nil)
 			if err != nil {
 				return err
 			}
 			return printJSON(data)
 		},
-	}
+	})
 
 	parent.AddCommand(ls, get, create, rm)
 	rootCmd.AddCommand(parent)
@@ -1,6 +1,6 @@
 module github.com/leechael/cio
 
-go 1.22
+go 1.23
 
 require (
 	github.com/cucumber/godog v0.15.0
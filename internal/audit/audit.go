@@ -0,0 +1,200 @@
+// Package audit records API calls that change data in a local JSON Lines
+// file or in syslog.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Destinations besides a file path.
+const (
+	Syslog = "syslog"
+	Off    = "off"
+)
+
+// Body recording modes.
+const (
+	BodyHash     = "hash"
+	BodyRedacted = "redacted"
+)
+
+// Entry is one audited request.
+type Entry struct {
+	Time       time.Time       `json:"time"`
+	User       string          `json:"user,omitempty"`
+	Profile    string          `json:"profile,omitempty"`
+	Region     string          `json:"region,omitempty"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	BodySHA256 string          `json:"body_sha256,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+	Status     int             `json:"status,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Command    string          `json:"command,omitempty"`
+}
+
+// Logger writes audit entries.
+type Logger interface {
+	Log(e Entry) error
+}
+
+// DefaultPath is $XDG_STATE_HOME/cio/audit.jsonl, falling back to
+// ~/.local/state.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "cio", "audit.jsonl"), nil
+}
+
+// Open returns the logger for a destination: a file path, Syslog, or Off
+// (nil logger).
+func Open(dest string) (Logger, error) {
+	switch dest {
+	case Off:
+		return nil, nil
+	case Syslog:
+		return openSyslog()
+	}
+	return &File{Path: dest}, nil
+}
+
+// File appends entries to a JSON Lines file, creating it with owner-only
+// permissions.
+type File struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (f *File) Log(e Entry) error {
+	line, err := marshal(e)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	// One write per line so concurrent cio processes do not interleave.
+	_, err = file.Write(line)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Read returns the entries in a file logged at or after since. A missing
+// file has no entries.
+func Read(path string, since time.Time) ([]Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, sc.Err()
+}
+
+// SetBody records body in e according to mode: a SHA-256 hash, or the
+// JSON structure with every string value replaced. Bodies that are not
+// JSON are always hashed.
+func (e *Entry) SetBody(body []byte, mode string) {
+	if len(body) == 0 {
+		return
+	}
+	var v any
+	if mode == BodyRedacted && json.Unmarshal(body, &v) == nil {
+		if data, err := marshal(redact(v)); err == nil {
+			e.Body = bytes.TrimSpace(data)
+			return
+		}
+	}
+	sum := sha256.Sum256(body)
+	e.BodySHA256 = hex.EncodeToString(sum[:])
+}
+
+// marshal encodes v as one line without escaping <, > and &, which keeps
+// "<redacted>" readable.
+func marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			v[k] = redact(x)
+		}
+	case []any:
+		for i, x := range v {
+			v[i] = redact(x)
+		}
+	case string:
+		return "<redacted>"
+	}
+	return v
+}
+
+// ParseSince accepts a duration before now (24h, 90m, 7d) or an absolute
+// time (RFC 3339 or 2006-01-02).
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if d, err := time.ParseDuration(days + "h"); err == nil {
+			return now.Add(-24 * d), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (want a duration like 24h or 7d, or a date)", s)
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileLogAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cio", "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, e := range []Entry{
+		{Time: old, Method: "DELETE", Path: "/v1/segments/1", Status: 204},
+		{Time: recent, Method: "POST", Path: "/v1/send/email", Status: 200},
+	} {
+		if err := l.Log(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v", info.Mode().Perm())
+	}
+
+	all, err := Read(path, time.Time{})
+	if err != nil || len(all) != 2 {
+		t.Fatalf("got %d entries, %v", len(all), err)
+	}
+	since, err := Read(path, old.Add(time.Hour))
+	if err != nil || len(since) != 1 || since[0].Path != "/v1/send/email" {
+		t.Fatalf("got %+v, %v", since, err)
+	}
+	missing, err := Read(filepath.Join(t.TempDir(), "none.jsonl"), time.Time{})
+	if err != nil || missing != nil {
+		t.Fatalf("got %v, %v", missing, err)
+	}
+}
+
+func TestOpenOff(t *testing.T) {
+	l, err := Open(Off)
+	if err != nil || l != nil {
+		t.Fatalf("got %v, %v", l, err)
+	}
+}
+
+func TestSetBody(t *testing.T) {
+	body := []byte(`{"to":"u@example.com","data":{"n":3,"tags":["vip"]}}`)
+
+	var e Entry
+	e.SetBody(body, BodyHash)
+	if len(e.BodySHA256) != 64 || e.Body != nil {
+		t.Fatalf("got %+v", e)
+	}
+
+	e = Entry{}
+	e.SetBody(body, BodyRedacted)
+	want := `{"data":{"n":3,"tags":["<redacted>"]},"to":"<redacted>"}`
+	if string(e.Body) != want || e.BodySHA256 != "" {
+		t.Fatalf("got %s", e.Body)
+	}
+
+	e = Entry{}
+	e.SetBody([]byte("not json"), BodyRedacted)
+	if e.BodySHA256 == "" || e.Body != nil {
+		t.Fatalf("non-JSON body should be hashed: %+v", e)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"24h":                  now.Add(-24 * time.Hour),
+		"7d":                   now.Add(-7 * 24 * time.Hour),
+		"90m":                  now.Add(-90 * time.Minute),
+		"2024-06-01T00:00:00Z": time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for in, want := range cases {
+		got, err := ParseSince(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("%s: got %v, %v", in, got, err)
+		}
+	}
+	if _, err := ParseSince("yesterday", now); err == nil || !strings.Contains(err.Error(), "--since") {
+		t.Errorf("expected error, got %v", err)
+	}
+}
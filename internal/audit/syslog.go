@@ -0,0 +1,25 @@
+//go:build !windows && !plan9
+
+package audit
+
+import "log/syslog"
+
+type syslogLogger struct {
+	w *syslog.Writer
+}
+
+func openSyslog() (Logger, error) {
+	w, err := syslog.New(syslog.LOG_NOTICE|syslog.LOG_USER, "cio")
+	if err != nil {
+		return nil, err
+	}
+	return &syslogLogger{w}, nil
+}
+
+func (s *syslogLogger) Log(e Entry) error {
+	line, err := marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.w.Notice(string(line))
+}
@@ -0,0 +1,9 @@
+//go:build windows || plan9
+
+package audit
+
+import "errors"
+
+func openSyslog() (Logger, error) {
+	return nil, errors.New("audit log: syslog is not available on this platform")
+}
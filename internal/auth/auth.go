@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/leechael/cio/internal/config"
+)
+
+// Token is a resolved App API token. Its String form describes where the
+// token came from and never includes the secret itself.
+type Token struct {
+	Value  string
+	Source string
+	Detail string
+}
+
+const (
+	SourceFile    = "file"
+	SourceCommand = "command"
+	SourceEnv     = "env"
+	SourceKeyring = "keyring"
+)
+
+func (t Token) String() string {
+	if t.Detail == "" {
+		return t.Source
+	}
+	return fmt.Sprintf("%s (%s)", t.Source, t.Detail)
+}
+
+// NoTokenError is returned when no source produced a token.
+type NoTokenError struct {
+	Profile  string
+	TokenEnv string
+}
+
+func (e *NoTokenError) Error() string {
+	return fmt.Sprintf("%s environment variable is not set (profile %q has no token_file, token_command or keyring entry either)", e.TokenEnv, e.Profile)
+}
+
+var (
+	mu    sync.Mutex
+	cache = make(map[string]Token)
+)
+
+// Resolve finds the App API token for a profile, trying in order: the
+// token file, the token command, the token environment variable and the
+// OS keyring. File, command and keyring lookups are cached for the life of
+// the process so a token_command runs at most once.
+func Resolve(p *config.Profile) (Token, error) {
+	if p.TokenFile != "" {
+		return cached("file\x00"+p.TokenFile, func() (Token, error) {
+			return readFile(p.TokenFile)
+		})
+	}
+	if p.TokenCommand != "" {
+		return cached("command\x00"+p.TokenCommand, func() (Token, error) {
+			return runCommand("token_command", p.TokenCommand)
+		})
+	}
+	env := p.TokenEnvName()
+	if v := strings.TrimSpace(os.Getenv(env)); v != "" {
+		return Token{Value: v, Source: SourceEnv, Detail: env}, nil
+	}
+	tok, err := cached("keyring\x00"+p.Name, func() (Token, error) {
+		v, err := KeyringGet(p.Name)
+		if err != nil {
+			return Token{}, err
+		}
+		return Token{Value: v, Source: SourceKeyring, Detail: p.Name}, nil
+	})
+	if err != nil {
+		return Token{}, err
+	}
+	if tok.Value == "" {
+		return Token{}, &NoTokenError{Profile: p.Name, TokenEnv: env}
+	}
+	return tok, nil
+}
+
+// TrackCredentials are the site ID and API key pair for the Track API.
+type TrackCredentials struct {
+	SiteID string
+	Key    Token
+}
+
+// ResolveTrack finds the Track API credentials for a profile. The site ID
+// comes from the profile or $CUSTOMERIO_SITE_ID; the key from the
+// profile's track_key_command or its track key environment variable.
+func ResolveTrack(p *config.Profile) (TrackCredentials, error) {
+	siteID := p.SiteID
+	if siteID == "" {
+		siteID = strings.TrimSpace(os.Getenv(config.DefaultSiteIDEnv))
+	}
+	if siteID == "" {
+		return TrackCredentials{}, fmt.Errorf("%s environment variable is not set (profile %q has no site_id either)", config.DefaultSiteIDEnv, p.Name)
+	}
+
+	if p.TrackKeyCommand != "" {
+		key, err := cached("command\x00"+p.TrackKeyCommand, func() (Token, error) {
+			return runCommand("track_key_command", p.TrackKeyCommand)
+		})
+		if err != nil {
+			return TrackCredentials{}, err
+		}
+		return TrackCredentials{SiteID: siteID, Key: key}, nil
+	}
+	env := p.TrackKeyEnvName()
+	if v := strings.TrimSpace(os.Getenv(env)); v != "" {
+		return TrackCredentials{SiteID: siteID, Key: Token{Value: v, Source: SourceEnv, Detail: env}}, nil
+	}
+	return TrackCredentials{}, fmt.Errorf("%s environment variable is not set (profile %q has no track_key_command either)", env, p.Name)
+}
+
+// ResolveWriteKey finds the Pipelines API write key for a profile: the
+// output of its write_key_command, or its write key environment variable.
+func ResolveWriteKey(p *config.Profile) (Token, error) {
+	if p.WriteKeyCommand != "" {
+		return cached("command\x00"+p.WriteKeyCommand, func() (Token, error) {
+			return runCommand("write_key_command", p.WriteKeyCommand)
+		})
+	}
+	env := p.WriteKeyEnvName()
+	if v := strings.TrimSpace(os.Getenv(env)); v != "" {
+		return Token{Value: v, Source: SourceEnv, Detail: env}, nil
+	}
+	return Token{}, fmt.Errorf("%s environment variable is not set (profile %q has no write_key_command either)", env, p.Name)
+}
+
+func cached(key string, fetch func() (Token, error)) (Token, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if tok, ok := cache[key]; ok {
+		return tok, nil
+	}
+	tok, err := fetch()
+	if err != nil {
+		return Token{}, err
+	}
+	cache[key] = tok
+	return tok, nil
+}
+
+func readFile(path string) (Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Token{}, fmt.Errorf("read token file: %w", err)
+	}
+	v := strings.TrimSpace(string(data))
+	if v == "" {
+		return Token{}, fmt.Errorf("token file %s is empty", path)
+	}
+	return Token{Value: v, Source: SourceFile, Detail: path}, nil
+}
+
+// runCommand executes a token_command, track_key_command or
+// write_key_command, named by key, through the shell. Stderr and the
+// terminal stay attached so tools like `op` can prompt, but input piped to
+// cio is never handed over: it holds the request body. Only stdout is
+// captured, and it is never included in error messages.
+func runCommand(key, command string) (Token, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	if tty := terminal(); tty != nil {
+		if tty != os.Stdin {
+			defer tty.Close()
+		}
+		cmd.Stdin = tty
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return Token{}, fmt.Errorf("%s failed: %w", key, err)
+	}
+	v := strings.TrimSpace(stdout.String())
+	if v == "" {
+		return Token{}, fmt.Errorf("%s printed nothing", key)
+	}
+	return Token{Value: v, Source: SourceCommand, Detail: command}, nil
+}
+
+// terminal returns stdin when it is a terminal, or else the controlling
+// terminal, or nil when there is none.
+func terminal() *os.File {
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		return os.Stdin
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	f, err := openTTY()
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+var openTTY = func() (*os.File, error) { return os.Open("/dev/tty") }
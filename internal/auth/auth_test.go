@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/leechael/cio/internal/config"
+)
+
+func resetCache(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	cache = make(map[string]Token)
+	mu.Unlock()
+}
+
+// fakeSecretTool installs a secret-tool stand-in backed by a directory.
+func fakeSecretTool(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script stand-in")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "secret-tool")
+	body := `#!/bin/sh
+store="` + dir + `/store"
+case "$1" in
+store) shift 3; cat > "$store.$4" ;;
+lookup) [ -f "$store.$5" ] || exit 1; cat "$store.$5" ;;
+clear) rm -f "$store.$5" ;;
+esac
+`
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	orig := secretTool
+	secretTool = script
+	t.Cleanup(func() { secretTool = orig })
+	return dir
+}
+
+func noKeyring(t *testing.T) {
+	t.Helper()
+	orig := secretTool
+	secretTool = filepath.Join(t.TempDir(), "missing-secret-tool")
+	t.Cleanup(func() { secretTool = orig })
+}
+
+func TestResolveEnv(t *testing.T) {
+	resetCache(t)
+	noKeyring(t)
+	t.Setenv("CUSTOMERIO_API_TOKEN", " tok \n")
+
+	tok, err := Resolve(&config.Profile{Name: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Value != "tok" || tok.String() != "env (CUSTOMERIO_API_TOKEN)" {
+		t.Fatalf("got %+v", tok)
+	}
+}
+
+func TestResolveFile(t *testing.T) {
+	resetCache(t)
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CUSTOMERIO_API_TOKEN", "env-token")
+
+	tok, err := Resolve(&config.Profile{Name: "default", TokenFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Value != "file-token" || tok.Source != SourceFile {
+		t.Fatalf("got %+v", tok)
+	}
+}
+
+func TestResolveEmptyFile(t *testing.T) {
+	resetCache(t)
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Resolve(&config.Profile{TokenFile: path}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestResolveCommandRunsOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	resetCache(t)
+	counter := filepath.Join(t.TempDir(), "count")
+	p := &config.Profile{Name: "default", TokenCommand: "echo x >> " + counter + "; echo cmd-token"}
+
+	for i := 0; i < 2; i++ {
+		tok, err := Resolve(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Value != "cmd-token" || tok.Source != SourceCommand {
+			t.Fatalf("got %+v", tok)
+		}
+	}
+	data, _ := os.ReadFile(counter)
+	if n := strings.Count(string(data), "x"); n != 1 {
+		t.Fatalf("command ran %d times", n)
+	}
+}
+
+func TestResolveCommandLeavesPipedStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	resetCache(t)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	_, _ = w.WriteString("request body")
+	w.Close()
+	origStdin, origTTY := os.Stdin, openTTY
+	os.Stdin = r
+	openTTY = func() (*os.File, error) { return nil, os.ErrNotExist }
+	defer func() { os.Stdin, openTTY = origStdin, origTTY }()
+
+	tok, err := Resolve(&config.Profile{TokenCommand: "cat; echo cmd-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Value != "cmd-token" {
+		t.Fatalf("command read stdin: %q", tok.Value)
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != "request body" {
+		t.Fatalf("stdin left = %q", rest)
+	}
+}
+
+func TestResolveCommandFailureHidesOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	resetCache(t)
+	_, err := Resolve(&config.Profile{TokenCommand: "echo secret-value; exit 3"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "secret-value") {
+		t.Fatalf("error leaks output: %v", err)
+	}
+}
+
+func TestResolveKeyring(t *testing.T) {
+	resetCache(t)
+	fakeSecretTool(t)
+	t.Setenv("CUSTOMERIO_API_TOKEN", "")
+
+	if err := KeyringSet("staging", "ring-token"); err != nil {
+		t.Fatal(err)
+	}
+	tok, err := Resolve(&config.Profile{Name: "staging"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Value != "ring-token" || tok.String() != "keyring (staging)" {
+		t.Fatalf("got %+v", tok)
+	}
+
+	if err := KeyringDelete("staging"); err != nil {
+		t.Fatal(err)
+	}
+	var noToken *NoTokenError
+	if _, err := Resolve(&config.Profile{Name: "staging"}); !errors.As(err, &noToken) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestResolveNoToken(t *testing.T) {
+	resetCache(t)
+	noKeyring(t)
+	t.Setenv("CUSTOMERIO_API_TOKEN", "")
+
+	_, err := Resolve(&config.Profile{Name: "default"})
+	var noToken *NoTokenError
+	if !errors.As(err, &noToken) {
+		t.Fatalf("got %v", err)
+	}
+	if !strings.Contains(err.Error(), "CUSTOMERIO_API_TOKEN") {
+		t.Fatalf("got %q", err.Error())
+	}
+}
+
+func TestKeyringUnavailable(t *testing.T) {
+	noKeyring(t)
+	if err := KeyringSet("p", "t"); !errors.Is(err, ErrKeyringUnavailable) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestResolveTrack(t *testing.T) {
+	t.Setenv("CUSTOMERIO_SITE_ID", "env-site")
+	t.Setenv("CUSTOMERIO_API_KEY", "env-key")
+
+	creds, err := ResolveTrack(&config.Profile{Name: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.SiteID != "env-site" || creds.Key.Value != "env-key" || creds.Key.String() != "env (CUSTOMERIO_API_KEY)" {
+		t.Fatalf("got %+v", creds)
+	}
+
+	t.Setenv("CIO_STAGING_TRACK_KEY", "staging-key")
+	creds, err = ResolveTrack(&config.Profile{Name: "staging", SiteID: "staging-site", TrackKeyEnv: "CIO_STAGING_TRACK_KEY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.SiteID != "staging-site" || creds.Key.Value != "staging-key" {
+		t.Fatalf("got %+v", creds)
+	}
+
+	t.Setenv("CUSTOMERIO_API_KEY", "")
+	_, err = ResolveTrack(&config.Profile{Name: "default"})
+	if err == nil || !strings.Contains(err.Error(), "CUSTOMERIO_API_KEY") {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestResolveWriteKey(t *testing.T) {
+	t.Setenv("CUSTOMERIO_WRITE_KEY", "wk")
+	key, err := ResolveWriteKey(&config.Profile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Value != "wk" || key.String() != "env (CUSTOMERIO_WRITE_KEY)" {
+		t.Fatalf("got %+v", key)
+	}
+
+	t.Setenv("CUSTOMERIO_WRITE_KEY", "")
+	if _, err := ResolveWriteKey(&config.Profile{}); err == nil || !strings.Contains(err.Error(), "CUSTOMERIO_WRITE_KEY") {
+		t.Fatalf("got %v", err)
+	}
+}
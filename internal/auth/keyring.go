@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The keyring is the freedesktop Secret Service, reached through
+// libsecret's secret-tool so no D-Bus bindings are needed. Entries are
+// keyed by service=cio and the profile name.
+const keyringService = "cio"
+
+var secretTool = "secret-tool"
+
+var ErrKeyringUnavailable = errors.New("OS keyring unavailable: secret-tool (libsecret) not found")
+
+// KeyringGet returns the stored token for a profile, or "" when there is
+// none or no keyring is available.
+func KeyringGet(profile string) (string, error) {
+	if _, err := exec.LookPath(secretTool); err != nil {
+		return "", nil
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(secretTool, "lookup", "service", keyringService, "profile", profile)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// secret-tool exits 1 when no matching item exists.
+			return "", nil
+		}
+		return "", fmt.Errorf("keyring lookup: %w", err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func KeyringSet(profile, token string) error {
+	if _, err := exec.LookPath(secretTool); err != nil {
+		return ErrKeyringUnavailable
+	}
+	cmd := exec.Command(secretTool, "store", "--label", "cio App API token ("+profile+")",
+		"service", keyringService, "profile", profile)
+	cmd.Stdin = strings.NewReader(token)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("keyring store: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	forget("keyring\x00" + profile)
+	return nil
+}
+
+func KeyringDelete(profile string) error {
+	if _, err := exec.LookPath(secretTool); err != nil {
+		return ErrKeyringUnavailable
+	}
+	cmd := exec.Command(secretTool, "clear", "service", keyringService, "profile", profile)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("keyring clear: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	forget("keyring\x00" + profile)
+	return nil
+}
+
+func forget(key string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(cache, key)
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Track API v2 batch limits.
+const (
+	MaxBatchBytes     = 500 * 1024
+	MaxOperationBytes = 32 * 1024
+)
+
+// TrackBatch sends operations in one /api/v2/batch request and returns one
+// error slot per operation, nil for those that were accepted. Rejections
+// Customer.io reports per operation (by batch_index) land in their own
+// slot; any other 4xx rejects the whole request and fills every remaining
+// slot, since sending it again would fail the same way. The returned error
+// means the outcome is unknown and the operations may or may not have been
+// applied: a network error or cancellation, a 401, 429 or 5xx.
+func (c *Client) TrackBatch(ctx context.Context, ops []json.RawMessage) ([]error, error) {
+	data, err := c.Post(ctx, "/api/v2/batch", map[string]any{"batch": ops})
+	results := make([]error, len(ops))
+
+	var apiErr *APIError
+	if err != nil {
+		if !errors.As(err, &apiErr) {
+			return nil, err
+		}
+		switch code := apiErr.StatusCode; {
+		case code == http.StatusUnauthorized, code == http.StatusTooManyRequests, code >= 500:
+			return nil, err
+		}
+		data = json.RawMessage(apiErr.Body)
+	}
+
+	var parsed struct {
+		Errors []batchError `json:"errors"`
+	}
+	_ = json.Unmarshal(data, &parsed)
+	matched := 0
+	for _, e := range parsed.Errors {
+		if e.BatchIndex == nil || *e.BatchIndex < 0 || *e.BatchIndex >= len(ops) || results[*e.BatchIndex] != nil {
+			continue
+		}
+		results[*e.BatchIndex] = e
+		matched++
+	}
+	if apiErr != nil && matched < len(ops) {
+		for i := range results {
+			if results[i] == nil {
+				results[i] = apiErr
+			}
+		}
+	}
+	return results, nil
+}
+
+// batchError is one entry of the "errors" array of a batch response.
+type batchError struct {
+	BatchIndex *int   `json:"batch_index"`
+	Reason     string `json:"reason"`
+	Field      string `json:"field"`
+	Message    string `json:"message"`
+}
+
+func (e batchError) Error() string {
+	var parts []string
+	for _, s := range []string{e.Reason, e.Field, e.Message} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	if len(parts) == 0 {
+		return "rejected"
+	}
+	return strings.Join(parts, ": ")
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func batchOps(n int) []json.RawMessage {
+	ops := make([]json.RawMessage, n)
+	for i := range ops {
+		ops[i] = json.RawMessage(`{"type":"person","action":"identify","identifiers":{"id":"1"}}`)
+	}
+	return ops
+}
+
+func TestTrackBatchPartialFailure(t *testing.T) {
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/batch" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var body struct {
+			Batch []json.RawMessage `json:"batch"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Batch) != 3 {
+			t.Errorf("batch has %d ops", len(body.Batch))
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`{"errors":[{"batch_index":1,"reason":"required","field":"identifiers","message":"missing"}]}`))
+	})
+
+	errs, err := c.TrackBatch(context.Background(), batchOps(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs[0] != nil || errs[2] != nil || errs[1] == nil || errs[1].Error() != "required: identifiers: missing" {
+		t.Fatalf("got %v", errs)
+	}
+}
+
+func TestTrackBatchRejected(t *testing.T) {
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errors":[{"batch_index":0,"reason":"invalid"},{"batch_index":1,"reason":"required"}]}`))
+	})
+
+	errs, err := c.TrackBatch(context.Background(), batchOps(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs[0] == nil || errs[0].Error() != "invalid" || errs[1] == nil || errs[1].Error() != "required" {
+		t.Fatalf("got %v", errs)
+	}
+}
+
+func TestTrackBatchRequestRejected(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		body   string
+		want   []string
+	}{
+		{http.StatusRequestEntityTooLarge, ``, []string{"", ""}},
+		{http.StatusUnprocessableEntity, `{"errors":[{"detail":"malformed batch"}]}`, []string{"malformed batch", "malformed batch"}},
+		// Only one of two operations has its own verdict.
+		{http.StatusBadRequest, `{"errors":[{"batch_index":1,"reason":"invalid"}]}`, []string{"", "invalid"}},
+	} {
+		c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			_, _ = w.Write([]byte(tt.body))
+		})
+
+		errs, err := c.TrackBatch(context.Background(), batchOps(2))
+		if err != nil {
+			t.Fatalf("%d: %v", tt.status, err)
+		}
+		for i, e := range errs {
+			var apiErr *APIError
+			if tt.want[i] == "invalid" {
+				if e == nil || e.Error() != "invalid" {
+					t.Errorf("%d: op %d: got %v", tt.status, i, e)
+				}
+			} else if !errors.As(e, &apiErr) || apiErr.StatusCode != tt.status || !strings.Contains(e.Error(), tt.want[i]) {
+				t.Errorf("%d: op %d: got %v", tt.status, i, e)
+			}
+		}
+	}
+}
+
+func TestTrackBatchOutcomeUnknown(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		body   string
+	}{
+		{http.StatusUnauthorized, ``},
+		{http.StatusTooManyRequests, ``},
+		{http.StatusBadGateway, `{"errors":[{"batch_index":0,"reason":"invalid"},{"batch_index":1,"reason":"invalid"}]}`},
+	} {
+		c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			_, _ = w.Write([]byte(tt.body))
+		})
+		c.MaxRetries = 0
+
+		errs, err := c.TrackBatch(context.Background(), batchOps(2))
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || errs != nil {
+			t.Errorf("%d: got %v, %v", tt.status, errs, err)
+		}
+	}
+}
+
+func TestTrackBatchTransportError(t *testing.T) {
+	c := &Client{BaseURL: "http://cio.invalid", HTTPClient: &http.Client{
+		Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection reset")
+		}),
+	}}
+
+	_, err := c.TrackBatch(context.Background(), batchOps(1))
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("got %v", err)
+	}
+}
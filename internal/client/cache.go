@@ -0,0 +1,262 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTLs are how long GET responses are kept, by resource: the
+// path element after /v1/. Resources without a TTL are never cached.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"segments":            5 * time.Minute,
+	"campaigns":           5 * time.Minute,
+	"index":               time.Hour,
+	"subscription_topics": time.Hour,
+}
+
+// Cache keeps successful App API GET responses on disk, one JSON file per
+// URL. Entries are keyed by Scope, the profile and region, as well as the
+// URL, so workspaces never see each other's data. A request that changes a
+// resource drops every cached response for it: DELETE /v1/segments/7
+// invalidates both segment 7 and the segment list.
+type Cache struct {
+	Dir   string
+	Scope string
+
+	// TTLs overrides DefaultCacheTTLs per resource; zero disables caching
+	// for that resource.
+	TTLs map[string]time.Duration
+
+	// Refresh skips lookups but still stores fresh responses.
+	Refresh bool
+}
+
+// CacheEntry is one cached response as stored on disk.
+type CacheEntry struct {
+	Scope   string          `json:"scope"`
+	URL     string          `json:"url"`
+	Path    string          `json:"path"`
+	Stored  time.Time       `json:"stored"`
+	Expires time.Time       `json:"expires"`
+	Hits    int             `json:"hits,omitempty"`
+	Body    json.RawMessage `json:"body"`
+}
+
+// DefaultCacheDir is the user cache directory's cio/http, e.g.
+// ~/.cache/cio/http.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cio", "http"), nil
+}
+
+// ParseCacheTTLs parses per-resource TTLs such as {"segments": "10m"}.
+func ParseCacheTTLs(pairs map[string]string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration, len(pairs))
+	for resource, v := range pairs {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid cache TTL %q for %s (want a duration like 10m, or 0 to disable)", v, resource)
+		}
+		ttls[resource] = d
+	}
+	return ttls, nil
+}
+
+func (c *Cache) ttl(path string) time.Duration {
+	resource := cacheResource(path)
+	if d, ok := c.TTLs[resource]; ok {
+		return d
+	}
+	return DefaultCacheTTLs[resource]
+}
+
+func (c *Cache) get(u, path string) ([]byte, bool) {
+	if c.Refresh || c.ttl(path) <= 0 {
+		return nil, false
+	}
+	file := c.file(u, path)
+	e, err := readCacheEntry(file)
+	if err != nil || e.Scope != c.Scope || e.URL != u || !now().Before(e.Expires) {
+		return nil, false
+	}
+	// The hit count is only for stats; failing to update it is harmless.
+	e.Hits++
+	_ = writeCacheEntry(file, e)
+	return e.Body, true
+}
+
+func (c *Cache) put(u, path string, body []byte) {
+	ttl := c.ttl(path)
+	if ttl <= 0 || !json.Valid(body) {
+		return
+	}
+	t := now()
+	_ = writeCacheEntry(c.file(u, path), &CacheEntry{
+		Scope:   c.Scope,
+		URL:     u,
+		Path:    path,
+		Stored:  t.UTC(),
+		Expires: t.Add(ttl).UTC(),
+		Body:    body,
+	})
+}
+
+func (c *Cache) invalidate(path string) {
+	resource := cacheResource(path)
+	if resource == "" {
+		return
+	}
+	files, _ := filepath.Glob(filepath.Join(c.Dir, resource+"-*.json"))
+	for _, f := range files {
+		if e, err := readCacheEntry(f); err != nil || e.Scope == c.Scope {
+			_ = os.Remove(f)
+		}
+	}
+}
+
+// file names start with the resource so invalidation and stats only read
+// the entries they need.
+func (c *Cache) file(u, path string) string {
+	sum := sha256.Sum256([]byte(c.Scope + "\n" + u))
+	return filepath.Join(c.Dir, cacheResource(path)+"-"+hex.EncodeToString(sum[:16])+".json")
+}
+
+// cacheResource is the path element after /v1/, or "" for paths outside
+// the App API.
+func cacheResource(path string) string {
+	rest, ok := strings.CutPrefix(path, "/v1/")
+	if !ok {
+		return ""
+	}
+	resource, _, _ := strings.Cut(rest, "/")
+	return resource
+}
+
+func readCacheEntry(file string) (*CacheEntry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var e CacheEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// writeCacheEntry writes through a temporary file so concurrent readers
+// never see half an entry.
+func writeCacheEntry(file string, e *CacheEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// CacheStat summarizes the cached responses for one resource.
+type CacheStat struct {
+	Resource string `json:"resource"`
+	Entries  int    `json:"entries"`
+	Expired  int    `json:"expired"`
+	Hits     int    `json:"hits"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// CacheStats summarizes every entry in dir, sorted by resource. A missing
+// directory has no entries.
+func CacheStats(dir string) ([]CacheStat, error) {
+	files, err := cacheFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	byResource := make(map[string]*CacheStat)
+	t := now()
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		e, err := readCacheEntry(f)
+		if err != nil {
+			continue
+		}
+		resource := cacheResource(e.Path)
+		s, ok := byResource[resource]
+		if !ok {
+			s = &CacheStat{Resource: resource}
+			byResource[resource] = s
+		}
+		s.Entries++
+		s.Hits += e.Hits
+		s.Bytes += info.Size()
+		if !t.Before(e.Expires) {
+			s.Expired++
+		}
+	}
+	stats := make([]CacheStat, 0, len(byResource))
+	for _, s := range byResource {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Resource < stats[j].Resource })
+	return stats, nil
+}
+
+// ClearCache removes the cached responses for the given resources, or all
+// of them, and returns how many were removed.
+func ClearCache(dir string, resources ...string) (int, error) {
+	var files []string
+	if len(resources) == 0 {
+		var err error
+		if files, err = cacheFiles(dir); err != nil {
+			return 0, err
+		}
+	}
+	for _, r := range resources {
+		matches, err := filepath.Glob(filepath.Join(dir, r+"-*.json"))
+		if err != nil {
+			return 0, err
+		}
+		files = append(files, matches...)
+	}
+	n := 0
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func cacheFiles(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*.json"))
+}
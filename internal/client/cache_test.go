@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func cachedClient(t *testing.T, scope string, dir string) (*Client, *int) {
+	t.Helper()
+	calls := new(int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		fmt.Fprintf(w, `{"call":%d}`, *calls)
+	}))
+	t.Cleanup(srv.Close)
+	return &Client{
+		BaseURL:    srv.URL,
+		Token:      "tok",
+		HTTPClient: srv.Client(),
+		Cache:      &Cache{Dir: dir, Scope: scope},
+	}, calls
+}
+
+func TestCacheHitAndExpiry(t *testing.T) {
+	clock := stubClock(t)
+	c, calls := cachedClient(t, "default/us", t.TempDir())
+	ctx := context.Background()
+
+	for range 2 {
+		data, err := c.Get(ctx, "/v1/segments", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `{"call":1}` {
+			t.Fatalf("got %s", data)
+		}
+	}
+	if *calls != 1 {
+		t.Fatalf("calls = %d, want 1", *calls)
+	}
+
+	*clock = clock.Add(DefaultCacheTTLs["segments"])
+	if data, _ := c.Get(ctx, "/v1/segments", nil); string(data) != `{"call":2}` {
+		t.Fatalf("expired entry served: %s", data)
+	}
+}
+
+func TestCacheOnlyConfiguredResources(t *testing.T) {
+	stubClock(t)
+	c, calls := cachedClient(t, "default/us", t.TempDir())
+	c.Cache.TTLs = map[string]time.Duration{"segments": 0}
+	for range 2 {
+		_, _ = c.Get(context.Background(), "/v1/segments", nil)
+		_, _ = c.Get(context.Background(), "/v1/customers/1/attributes", nil)
+	}
+	if *calls != 4 {
+		t.Fatalf("calls = %d, want 4", *calls)
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	stubClock(t)
+	c, calls := cachedClient(t, "default/us", t.TempDir())
+	ctx := context.Background()
+	_, _ = c.Get(ctx, "/v1/campaigns", nil)
+	c.Cache.Refresh = true
+	_, _ = c.Get(ctx, "/v1/campaigns", nil)
+	c.Cache.Refresh = false
+	data, _ := c.Get(ctx, "/v1/campaigns", nil)
+	if *calls != 2 || string(data) != `{"call":2}` {
+		t.Fatalf("calls = %d, data = %s", *calls, data)
+	}
+}
+
+func TestCacheScope(t *testing.T) {
+	stubClock(t)
+	dir := t.TempDir()
+	a, calls := cachedClient(t, "prod/us", dir)
+	b, _ := cachedClient(t, "staging/us", dir)
+	b.BaseURL, b.HTTPClient = a.BaseURL, a.HTTPClient
+	ctx := context.Background()
+	_, _ = a.Get(ctx, "/v1/segments", nil)
+	_, _ = b.Get(ctx, "/v1/segments", nil)
+	if *calls != 2 {
+		t.Fatal("a response cached for one profile was served to another")
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	stubClock(t)
+	c, calls := cachedClient(t, "default/us", t.TempDir())
+	ctx := context.Background()
+	_, _ = c.Get(ctx, "/v1/segments", nil)
+	_, _ = c.Get(ctx, "/v1/segments/7", nil)
+	_, _ = c.Get(ctx, "/v1/campaigns", nil)
+	if _, err := c.Delete(ctx, "/v1/segments/7", nil); err != nil {
+		t.Fatal(err)
+	}
+	*calls = 0
+	_, _ = c.Get(ctx, "/v1/segments", nil)
+	_, _ = c.Get(ctx, "/v1/segments/7", nil)
+	_, _ = c.Get(ctx, "/v1/campaigns", nil)
+	if *calls != 2 {
+		t.Fatalf("calls = %d, want 2 (segments refetched, campaigns cached)", *calls)
+	}
+}
+
+func TestCacheStatsAndClear(t *testing.T) {
+	clock := stubClock(t)
+	dir := t.TempDir()
+	c, _ := cachedClient(t, "default/us", dir)
+	ctx := context.Background()
+	_, _ = c.Get(ctx, "/v1/segments", nil)
+	_, _ = c.Get(ctx, "/v1/segments", nil)
+	_, _ = c.Get(ctx, "/v1/index/attributes", nil)
+	*clock = clock.Add(time.Hour / 2)
+
+	stats, err := CacheStats(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	idx, seg := stats[0], stats[1]
+	if idx.Resource != "index" || idx.Entries != 1 || idx.Expired != 0 {
+		t.Fatalf("index stats = %+v", idx)
+	}
+	if seg.Resource != "segments" || seg.Entries != 1 || seg.Expired != 1 || seg.Hits != 1 || seg.Bytes == 0 {
+		t.Fatalf("segments stats = %+v", seg)
+	}
+
+	if n, err := ClearCache(dir, "segments"); err != nil || n != 1 {
+		t.Fatalf("ClearCache(segments) = %d, %v", n, err)
+	}
+	if n, err := ClearCache(dir); err != nil || n != 1 {
+		t.Fatalf("ClearCache() = %d, %v", n, err)
+	}
+	if stats, _ := CacheStats(dir); len(stats) != 0 {
+		t.Fatalf("stats after clear = %+v", stats)
+	}
+}
+
+func TestParseCacheTTLs(t *testing.T) {
+	ttls, err := ParseCacheTTLs(map[string]string{"segments": "10m", "campaigns": "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttls["segments"] != 10*time.Minute || ttls["campaigns"] != 0 {
+		t.Fatalf("got %v", ttls)
+	}
+	if _, err := ParseCacheTTLs(map[string]string{"segments": "soon"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,293 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A cassette is a directory of recorded interactions, one JSON file each,
+// named NNN-METHOD-path.json in the order they happened. Only the path and
+// query are stored, so a cassette replays against any region or base URL.
+
+// ErrNotRecorded is returned in replay mode for a request the cassette has
+// no interaction for.
+var ErrNotRecorded = errors.New("no recorded interaction")
+
+// Interaction is one request/response pair in a cassette.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status int             `json:"status"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// scrubbedHeaders never reach a cassette: credentials, plus headers that
+// would go stale when a cassette is edited by hand.
+var scrubbedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Date", "Content-Length"}
+
+// Recorder sends requests through Base and saves each exchange to Dir,
+// numbering after any interactions already there. Downloads pass through
+// unrecorded.
+type Recorder struct {
+	Base http.RoundTripper
+	Dir  string
+
+	mu   sync.Mutex
+	next int
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isDownload(req) {
+		// Export files are streamed to disk, not kept in a cassette.
+		return r.Base.RoundTrip(req)
+	}
+	reqBody, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	in := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.RequestURI(),
+			Header: scrub(req.Header),
+		},
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: scrub(resp.Header),
+		},
+	}
+	in.Request.Body, in.Request.Text = splitBody(reqBody)
+	in.Response.Body, in.Response.Text = splitBody(respBody)
+	if err := r.save(in); err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *Recorder) save(in Interaction) error {
+	data, err := json.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next == 0 {
+		if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+			return err
+		}
+		existing, err := cassetteFiles(r.Dir)
+		if err != nil {
+			return err
+		}
+		r.next = len(existing) + 1
+	}
+	name := fmt.Sprintf("%03d-%s-%s.json", r.next, in.Request.Method, slug(in.Request.URL))
+	r.next++
+	return os.WriteFile(filepath.Join(r.Dir, name), append(data, '\n'), 0o644)
+}
+
+// Replayer answers requests from a cassette without touching the network.
+// Each interaction is used once, in order; when a request has been seen
+// more often than it was recorded, its last interaction is repeated.
+type Replayer struct {
+	dir          string
+	interactions []Interaction
+	used         []bool
+	mu           sync.Mutex
+}
+
+// LoadCassette reads every interaction in dir.
+func LoadCassette(dir string) (*Replayer, error) {
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("load cassette: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("load cassette: no interactions in %s", dir)
+	}
+	r := &Replayer{dir: dir}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var in Interaction
+		if err := json.Unmarshal(data, &in); err != nil {
+			return nil, fmt.Errorf("load cassette %s: %w", f, err)
+		}
+		r.interactions = append(r.interactions, in)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := requestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	in, ok := r.match(req.Method, req.URL.RequestURI(), body)
+	if !ok {
+		return nil, fmt.Errorf("replay %s %s from %s: %w", req.Method, req.URL.RequestURI(), r.dir, ErrNotRecorded)
+	}
+
+	// Bodies are indented on disk; send them back compact.
+	respBody := []byte(in.Response.Text)
+	if len(in.Response.Body) > 0 {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, in.Response.Body); err != nil {
+			return nil, err
+		}
+		respBody = buf.Bytes()
+	}
+	header := in.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+		StatusCode:    in.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// match prefers the first unused interaction with the same method, URL and
+// body, then one with a different body, then the last one used.
+func (r *Replayer) match(method, uri string, body []byte) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wantBody, wantText := splitBody(body)
+	best, last := -1, -1
+	for i, in := range r.interactions {
+		if in.Request.Method != method || in.Request.URL != uri {
+			continue
+		}
+		last = i
+		if r.used[i] {
+			continue
+		}
+		if jsonEqual(in.Request.Body, wantBody) && in.Request.Text == wantText {
+			best = i
+			break
+		}
+		if best < 0 {
+			best = i
+		}
+	}
+	if best < 0 {
+		best = last
+	}
+	if best < 0 {
+		return Interaction{}, false
+	}
+	r.used[best] = true
+	return r.interactions[best], true
+}
+
+func cassetteFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(files)
+	return files, err
+}
+
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.GetBody == nil {
+		return nil, nil
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// splitBody keeps JSON bodies as JSON so cassettes stay readable, and
+// anything else as text.
+func splitBody(data []byte) (json.RawMessage, string) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ""
+	}
+	if json.Valid(data) {
+		var buf bytes.Buffer
+		if json.Compact(&buf, data) == nil {
+			return buf.Bytes(), ""
+		}
+	}
+	return nil, string(data)
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var x, y any
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return bytes.Equal(a, b)
+	}
+	xs, _ := json.Marshal(x)
+	ys, _ := json.Marshal(y)
+	return bytes.Equal(xs, ys)
+}
+
+func scrub(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range scrubbedHeaders {
+		out.Del(name)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func slug(uri string) string {
+	path, _, _ := strings.Cut(uri, "?")
+	s := strings.Trim(strings.ReplaceAll(path, "/", "_"), "_")
+	if len(s) > 60 {
+		s = s[:60]
+	}
+	if s == "" {
+		s = "root"
+	}
+	return s
+}
@@ -2,60 +2,262 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"strings"
+	"time"
+
+	"github.com/leechael/cio/internal/auth"
+	"github.com/leechael/cio/internal/config"
 )
 
+const DefaultTimeout = 60 * time.Second
+
 type Client struct {
 	BaseURL    string
 	Token      string
 	HTTPClient *http.Client
+
+	// Username switches the client from Bearer to HTTP Basic auth, with
+	// Token as the password. The Track API authenticates this way, and so
+	// does Pipelines, with the write key as Username and no Token.
+	Username string
+
+	// TokenSource describes where Token was found, e.g. "env
+	// (CUSTOMERIO_API_TOKEN)". It is safe to display.
+	TokenSource string
+
+	// MaxRetries is how many times a failed request is retried; zero
+	// disables retries. RetryMaxWait caps the pause between attempts.
+	MaxRetries   int
+	RetryMaxWait time.Duration
+
+	// Limiter throttles requests per endpoint class; nil disables it.
+	// RateClass, when set, puts every request in that class instead of
+	// deriving it from the path.
+	Limiter   *Limiter
+	RateClass string
+
+	// Timeout bounds each HTTP attempt; zero means no limit.
+	Timeout time.Duration
+
+	// Header is added to every request, replacing same-named defaults.
+	Header http.Header
+
+	// ReadOnly refuses every request except GET, HEAD and a few POST
+	// searches with a *ReadOnlyError.
+	ReadOnly bool
+
+	// Audit, when set, is called after every request with side effects
+	// that was sent; requests refused by ReadOnly or DryRun are not.
+	Audit func(AuditRecord)
+
+	// DryRun, when set, prints requests with side effects instead of
+	// sending them; those calls return ErrDryRun.
+	DryRun *DryRun
+
+	// Cache, when set, answers GETs from disk and is invalidated by
+	// requests that change the same resource.
+	Cache *Cache
 }
 
-func New(region string) (*Client, error) {
-	token := os.Getenv("CUSTOMERIO_API_TOKEN")
-	if token == "" {
-		return nil, fmt.Errorf("CUSTOMERIO_API_TOKEN environment variable is not set")
+// New builds an App API client from a profile, resolving the token with
+// auth.Resolve. $CIO_BASE_URL overrides the profile's base URL, which in
+// turn overrides the region default.
+func New(p *config.Profile) (*Client, error) {
+	token, err := auth.Resolve(p)
+	if err != nil {
+		return nil, err
 	}
 
 	baseURL := os.Getenv("CIO_BASE_URL")
+	if baseURL == "" {
+		baseURL = p.BaseURL
+	}
 	if baseURL == "" {
 		baseURL = "https://api.customer.io"
-		if region == "eu" {
+		if p.Region == "eu" {
 			baseURL = "https://api-eu.customer.io"
 		}
 	}
 
 	return &Client{
-		BaseURL:    baseURL,
-		Token:      token,
-		HTTPClient: &http.Client{},
+		BaseURL:      baseURL,
+		Token:        token.Value,
+		TokenSource:  token.String(),
+		HTTPClient:   &http.Client{},
+		MaxRetries:   DefaultMaxRetries,
+		RetryMaxWait: DefaultRetryMaxWait,
+		Limiter:      NewLimiter(nil),
+		Timeout:      DefaultTimeout,
 	}, nil
 }
 
-func (c *Client) do(method, path string, query url.Values, body io.Reader) (json.RawMessage, error) {
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader) (json.RawMessage, error) {
 	u := c.BaseURL + path
 	if len(query) > 0 {
 		u += "?" + query.Encode()
 	}
 
-	req, err := http.NewRequest(method, u, body)
-	if err != nil {
-		return nil, err
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = io.ReadAll(body); err != nil {
+			return nil, err
+		}
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.Token)
-	req.Header.Set("Content-Type", "application/json")
+	if c.ReadOnly && !allowedReadOnly(method, path) {
+		return nil, &ReadOnlyError{Method: method, Path: path}
+	}
+	if c.DryRun.intercepts(method) {
+		req, err := c.newRequest(ctx, method, u, payload)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.DryRun.write(req, payload); err != nil {
+			return nil, err
+		}
+		return nil, ErrDryRun
+	}
+
+	if method == http.MethodGet && c.Cache != nil {
+		if data, ok := c.Cache.get(u, path); ok {
+			return data, nil
+		}
+	}
+
+	class := c.RateClass
+	if class == "" {
+		class = endpointClass(path)
+	}
+	for attempt := 0; ; attempt++ {
+		if c.Limiter != nil {
+			if err := c.Limiter.Wait(ctx, class); err != nil {
+				return nil, err
+			}
+		}
+		data, resp, err := c.roundTrip(ctx, method, u, payload)
+		if ctx.Err() == nil && attempt < c.MaxRetries && shouldRetry(method, resp, err) {
+			if wait, ok := retryWait(attempt, resp, c.RetryMaxWait); ok {
+				if err := sleep(ctx, wait); err != nil {
+					c.audit(method, path, payload, resp, err)
+					c.cacheResult(method, path, u, nil, err)
+					return nil, err
+				}
+				continue
+			}
+		}
+		c.audit(method, path, payload, resp, err)
+		c.cacheResult(method, path, u, data, err)
+		return data, err
+	}
+}
 
+// cacheResult stores a successful GET and drops the cached responses for a
+// resource after any request that may have changed it, even a failed one.
+func (c *Client) cacheResult(method, path, u string, data []byte, err error) {
+	switch {
+	case c.Cache == nil || method == http.MethodHead:
+	case method == http.MethodGet:
+		if err == nil {
+			c.Cache.put(u, path, data)
+		}
+	default:
+		c.Cache.invalidate(path)
+	}
+}
+
+// AuditRecord describes a request with side effects once its last attempt
+// has finished.
+type AuditRecord struct {
+	Method string
+	Path   string
+	Body   []byte
+	Status int // zero when no response was received
+	Err    error
+}
+
+func (c *Client) audit(method, path string, payload []byte, resp *http.Response, err error) {
+	if c.Audit == nil || method == http.MethodGet || method == http.MethodHead {
+		return
+	}
+	rec := AuditRecord{Method: method, Path: path, Body: payload, Err: err}
+	if resp != nil {
+		rec.Status = resp.StatusCode
+	}
+	c.Audit(rec)
+}
+
+// WrapTransport replaces c's transport with wrap(current), copying the
+// http.Client so a shared client is left untouched.
+func (c *Client) WrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	hc := &http.Client{}
+	if c.HTTPClient != nil {
+		*hc = *c.HTTPClient
+	}
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	hc.Transport = wrap(base)
+	c.HTTPClient = hc
+}
+
+// roundTrip performs a single attempt, bounded by c.Timeout. The response
+// is returned with its body already consumed so the caller can inspect the
+// status and headers when deciding whether to retry; it is nil when the
+// request failed before a complete response was read.
+func (c *Client) roundTrip(ctx context.Context, method, u string, payload []byte) (json.RawMessage, *http.Response, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	req, err := c.newRequest(ctx, method, u, payload)
+	if err != nil {
+		return nil, nil, err
+	}
 	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := readResponse(resp)
+	if err != nil {
+		if _, ok := err.(*APIError); !ok {
+			return nil, nil, err
+		}
+	}
+	return data, resp, err
+}
+
+func (c *Client) newRequest(ctx context.Context, method, u string, payload []byte) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
 	if err != nil {
 		return nil, err
 	}
+
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	return req, nil
+}
+
+func readResponse(resp *http.Response) (json.RawMessage, error) {
 	defer resp.Body.Close()
 
 	data, err := io.ReadAll(resp.Body)
@@ -64,11 +266,7 @@ func (c *Client) do(method, path string, query url.Values, body io.Reader) (json
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		msg := strings.TrimSpace(string(data))
-		if msg == "" {
-			msg = resp.Status
-		}
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, msg)
+		return nil, newAPIError(resp, data)
 	}
 
 	if len(data) == 0 {
@@ -78,28 +276,34 @@ func (c *Client) do(method, path string, query url.Values, body io.Reader) (json
 	return json.RawMessage(data), nil
 }
 
-func (c *Client) Get(path string, query url.Values) (json.RawMessage, error) {
-	return c.do(http.MethodGet, path, query, nil)
+// Do sends a request with a raw body, for callers that build their own
+// payloads.
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, body io.Reader) (json.RawMessage, error) {
+	return c.do(ctx, method, path, query, body)
+}
+
+func (c *Client) Get(ctx context.Context, path string, query url.Values) (json.RawMessage, error) {
+	return c.do(ctx, http.MethodGet, path, query, nil)
 }
 
-func (c *Client) Post(path string, body any) (json.RawMessage, error) {
+func (c *Client) Post(ctx context.Context, path string, body any) (json.RawMessage, error) {
 	b, err := encodeBody(body)
 	if err != nil {
 		return nil, err
 	}
-	return c.do(http.MethodPost, path, nil, b)
+	return c.do(ctx, http.MethodPost, path, nil, b)
 }
 
-func (c *Client) Put(path string, body any) (json.RawMessage, error) {
+func (c *Client) Put(ctx context.Context, path string, body any) (json.RawMessage, error) {
 	b, err := encodeBody(body)
 	if err != nil {
 		return nil, err
 	}
-	return c.do(http.MethodPut, path, nil, b)
+	return c.do(ctx, http.MethodPut, path, nil, b)
 }
 
-func (c *Client) Delete(path string, query url.Values) (json.RawMessage, error) {
-	return c.do(http.MethodDelete, path, query, nil)
+func (c *Client) Delete(ctx context.Context, path string, query url.Values) (json.RawMessage, error) {
+	return c.do(ctx, http.MethodDelete, path, query, nil)
 }
 
 func encodeBody(body any) (io.Reader, error) {
@@ -1,18 +1,24 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/leechael/cio/internal/config"
 )
 
 func TestNew(t *testing.T) {
 	t.Run("missing token", func(t *testing.T) {
 		t.Setenv("CUSTOMERIO_API_TOKEN", "")
-		_, err := New("us")
+		_, err := New(&config.Profile{Region: "us"})
 		if err == nil {
 			t.Fatal("expected error for missing token")
 		}
@@ -20,7 +26,7 @@ func TestNew(t *testing.T) {
 
 	t.Run("us region", func(t *testing.T) {
 		t.Setenv("CUSTOMERIO_API_TOKEN", "tok")
-		c, err := New("us")
+		c, err := New(&config.Profile{Region: "us"})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -31,7 +37,7 @@ func TestNew(t *testing.T) {
 
 	t.Run("eu region", func(t *testing.T) {
 		t.Setenv("CUSTOMERIO_API_TOKEN", "tok")
-		c, err := New("eu")
+		c, err := New(&config.Profile{Region: "eu"})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -40,9 +46,33 @@ func TestNew(t *testing.T) {
 		}
 	})
 
+	t.Run("profile token env", func(t *testing.T) {
+		t.Setenv("CUSTOMERIO_API_TOKEN", "")
+		t.Setenv("CIO_STAGING_TOKEN", "staging")
+		c, err := New(&config.Profile{TokenEnv: "CIO_STAGING_TOKEN"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Token != "staging" {
+			t.Fatalf("got %s", c.Token)
+		}
+	})
+
+	t.Run("profile base url", func(t *testing.T) {
+		t.Setenv("CUSTOMERIO_API_TOKEN", "tok")
+		t.Setenv("CIO_BASE_URL", "")
+		c, err := New(&config.Profile{Region: "eu", BaseURL: "https://proxy.example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.BaseURL != "https://proxy.example.com" {
+			t.Fatalf("got %s", c.BaseURL)
+		}
+	})
+
 	t.Run("unknown region defaults to us", func(t *testing.T) {
 		t.Setenv("CUSTOMERIO_API_TOKEN", "tok")
-		c, err := New("asia")
+		c, err := New(&config.Profile{Region: "asia"})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -70,7 +100,7 @@ func TestGet(t *testing.T) {
 			}
 			_, _ = w.Write([]byte(`{"ok":true}`))
 		})
-		data, err := c.Get("/v1/test", nil)
+		data, err := c.Get(context.Background(), "/v1/test", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -87,7 +117,7 @@ func TestGet(t *testing.T) {
 			_, _ = w.Write([]byte(`{}`))
 		})
 		q := url.Values{"email": {"a@b.com"}}
-		_, err := c.Get("/v1/test", q)
+		_, err := c.Get(context.Background(), "/v1/test", q)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -98,7 +128,7 @@ func TestGet(t *testing.T) {
 			w.WriteHeader(401)
 			_, _ = w.Write([]byte(`{"error":"unauthorized"}`))
 		})
-		_, err := c.Get("/v1/test", nil)
+		_, err := c.Get(context.Background(), "/v1/test", nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -112,7 +142,7 @@ func TestGet(t *testing.T) {
 		c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(500)
 		})
-		_, err := c.Get("/v1/test", nil)
+		_, err := c.Get(context.Background(), "/v1/test", nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -122,7 +152,7 @@ func TestGet(t *testing.T) {
 		c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(204)
 		})
-		data, err := c.Get("/v1/test", nil)
+		data, err := c.Get(context.Background(), "/v1/test", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -144,7 +174,7 @@ func TestPost(t *testing.T) {
 			}
 			_, _ = w.Write([]byte(`{"id":1}`))
 		})
-		data, err := c.Post("/v1/test", json.RawMessage(`{"name":"test"}`))
+		data, err := c.Post(context.Background(), "/v1/test", json.RawMessage(`{"name":"test"}`))
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -161,7 +191,7 @@ func TestPost(t *testing.T) {
 			}
 			_, _ = w.Write([]byte(`{}`))
 		})
-		_, err := c.Post("/v1/test", nil)
+		_, err := c.Post(context.Background(), "/v1/test", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -178,7 +208,7 @@ func TestPost(t *testing.T) {
 			}
 			_, _ = w.Write([]byte(`{}`))
 		})
-		_, err := c.Post("/v1/test", req{Name: "hello"})
+		_, err := c.Post(context.Background(), "/v1/test", req{Name: "hello"})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -192,7 +222,7 @@ func TestPut(t *testing.T) {
 		}
 		_, _ = w.Write([]byte(`{"updated":true}`))
 	})
-	data, err := c.Put("/v1/test", json.RawMessage(`{}`))
+	data, err := c.Put(context.Background(), "/v1/test", json.RawMessage(`{}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -208,7 +238,7 @@ func TestDelete(t *testing.T) {
 		}
 		w.WriteHeader(204)
 	})
-	data, err := c.Delete("/v1/test", nil)
+	data, err := c.Delete(context.Background(), "/v1/test", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -217,6 +247,28 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestDoWithHeader(t *testing.T) {
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("method = %s", r.Method)
+		}
+		if r.Header.Get("Content-Type") != "text/plain" || r.Header.Get("X-Extra") != "1" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	})
+	c.Header = http.Header{"Content-Type": {"text/plain"}, "X-Extra": {"1"}}
+
+	data, err := c.Do(context.Background(), http.MethodPatch, "/v1/test", nil, strings.NewReader("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hi" {
+		t.Fatalf("got %s", data)
+	}
+}
+
 func TestEncodeBody(t *testing.T) {
 	t.Run("nil", func(t *testing.T) {
 		r, err := encodeBody(nil)
@@ -268,3 +320,74 @@ func TestEncodeBody(t *testing.T) {
 		}
 	})
 }
+
+func TestTimeout(t *testing.T) {
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	c.Timeout = 20 * time.Millisecond
+
+	_, err := c.Get(context.Background(), "/v1/test", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	stubSleep(t)
+	calls := 0
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{}`))
+	})
+	c.MaxRetries = 3
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.Get(ctx, "/v1/test", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("calls = %d", calls)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	var got []AuditRecord
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client(), Audit: func(r AuditRecord) { got = append(got, r) }}
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "/v1/segments", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Post(ctx, "/v1/segments", map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Delete(ctx, "/v1/segments/9", nil); err == nil {
+		t.Fatal("expected 404")
+	}
+	c.DryRun = &DryRun{Out: io.Discard}
+	_, _ = c.Put(ctx, "/v1/snippets", nil)
+
+	if len(got) != 2 {
+		t.Fatalf("got %d records: %+v", len(got), got)
+	}
+	if got[0].Method != "POST" || got[0].Status != 200 || string(got[0].Body) != `{"a":1}` || got[0].Err != nil {
+		t.Errorf("post record: %+v", got[0])
+	}
+	if got[1].Path != "/v1/segments/9" || got[1].Status != 404 || got[1].Err == nil {
+		t.Errorf("delete record: %+v", got[1])
+	}
+}
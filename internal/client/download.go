@@ -0,0 +1,302 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrIncompleteDownload is returned when a download ends before the size
+// the server announced.
+var ErrIncompleteDownload = errors.New("incomplete download")
+
+// Download describes a file to fetch from a URL that needs no API
+// credentials, such as an export's signed link.
+//
+// The raw bytes are appended to Path+".part" as they arrive. When the
+// connection drops, the rest is requested with a Range header, up to
+// MaxRetries times; running the download again later resumes from the part
+// file the same way. Transform, when set, turns the raw stream into the
+// contents of Path while it downloads; otherwise the part file becomes Path.
+type Download struct {
+	URL       string
+	Path      string
+	Transform func(dst io.Writer, src io.Reader) error
+
+	// Progress is called as bytes arrive with the raw bytes so far and the
+	// total size, or -1 when the server did not say.
+	Progress func(done, total int64)
+}
+
+// Download fetches d.URL to d.Path. Requests go through the client's
+// transport but carry no credentials, and c.Timeout does not apply: large
+// exports take as long as they take. Transport wrappers see them marked as
+// downloads, so the body is streamed rather than captured and the signed
+// query is kept out of logs and traces.
+func (c *Client) Download(ctx context.Context, d Download) error {
+	part := d.Path + ".part"
+	w, err := os.OpenFile(part, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	info, err := w.Stat()
+	if err != nil {
+		return err
+	}
+
+	r := &rangeReader{c: c, ctx: ctx, url: d.URL, progress: d.Progress}
+	if err := r.open(info.Size()); err != nil {
+		return err
+	}
+	defer r.Close()
+	saved := r.offset
+	if saved == 0 && info.Size() > 0 {
+		// The server ignored the Range header; start over.
+		if err := w.Truncate(0); err != nil {
+			return err
+		}
+	}
+	if r.progress != nil {
+		r.progress(r.offset, r.total)
+	}
+
+	if d.Transform == nil {
+		if _, err := io.Copy(w, r); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		return os.Rename(part, d.Path)
+	}
+
+	// The transform reads what was saved by earlier runs, then the rest of
+	// the download as it is appended to the part file.
+	prev, err := os.Open(part)
+	if err != nil {
+		return err
+	}
+	defer prev.Close()
+	src := io.MultiReader(io.LimitReader(prev, saved), io.TeeReader(r, w))
+
+	tmp := d.Path + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	err = d.Transform(out, src)
+	if err == nil {
+		// Whatever the transform left unread still has to be saved and
+		// counted against the expected size.
+		_, err = io.Copy(io.Discard, src)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, d.Path); err != nil {
+		return err
+	}
+	return os.Remove(part)
+}
+
+// rangeReader reads a download from offset onwards, reconnecting with a
+// Range request when the body fails part way, and fails with
+// ErrIncompleteDownload if it ends short of total.
+type rangeReader struct {
+	c        *Client
+	ctx      context.Context
+	url      string
+	progress func(done, total int64)
+
+	body    io.ReadCloser
+	offset  int64
+	total   int64
+	retries int
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if n > 0 && r.progress != nil {
+			r.progress(r.offset, r.total)
+		}
+		if err == io.EOF && r.total >= 0 && r.offset < r.total {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		if r.ctx.Err() != nil || r.retries >= r.c.MaxRetries {
+			if r.total >= 0 {
+				err = fmt.Errorf("%w: got %d of %d bytes (%v); run it again to resume", ErrIncompleteDownload, r.offset, r.total, err)
+			}
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+		wait, _ := retryWait(r.retries, nil, r.c.RetryMaxWait)
+		r.retries++
+		if err := sleep(r.ctx, wait); err != nil {
+			return 0, err
+		}
+		want := r.offset
+		r.body.Close()
+		if err := r.open(want); err != nil {
+			return 0, err
+		}
+		if r.offset != want {
+			return 0, fmt.Errorf("%w: the server cannot resume at byte %d", ErrIncompleteDownload, want)
+		}
+	}
+}
+
+func (r *rangeReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	return r.body.Close()
+}
+
+// open requests the download from byte from, retrying like any GET. The
+// server may answer with the whole file instead, which resets offset.
+func (r *rangeReader) open(from int64) error {
+	for attempt := 0; ; attempt++ {
+		resp, err := r.request(from)
+		if err == nil {
+			return r.start(resp, from)
+		}
+		if r.ctx.Err() != nil || attempt >= r.c.MaxRetries || !shouldRetry(http.MethodGet, resp, err) {
+			return err
+		}
+		wait, ok := retryWait(attempt, resp, r.c.RetryMaxWait)
+		if !ok {
+			return err
+		}
+		if err := sleep(r.ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// request returns the response for a usable status, or an error together
+// with the response that caused it.
+func (r *rangeReader) request(from int64) (*http.Response, error) {
+	ctx := context.WithValue(r.ctx, downloadKey{}, true)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Byte offsets must refer to the file as stored, so the transport may
+	// not decompress it.
+	req.Header.Set("Accept-Encoding", "identity")
+	if from > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", from))
+	}
+	resp, err := r.c.HTTPClient.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			uerr.URL = loggedURL(req)
+		}
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+		return resp, nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	return resp, newAPIError(resp, data)
+}
+
+func (r *rangeReader) start(resp *http.Response, from int64) error {
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != from {
+			resp.Body.Close()
+			return fmt.Errorf("unexpected Content-Range %q for a download resumed at byte %d", resp.Header.Get("Content-Range"), from)
+		}
+		r.body, r.offset, r.total = resp.Body, start, total
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		// The part file already holds the whole download.
+		if _, total, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && total == from {
+			r.body, r.offset, r.total = http.NoBody, from, total
+			return nil
+		}
+		return r.open(0)
+	default:
+		r.body, r.offset, r.total = resp.Body, 0, resp.ContentLength
+	}
+	return nil
+}
+
+// parseContentRange parses "bytes 100-199/1000" and "bytes */1000". The
+// total is -1 when given as "*".
+func parseContentRange(v string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(v, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		t, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total = t
+	}
+	if rng == "*" {
+		return 0, total, true
+	}
+	first, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// downloadKey marks the context of a download request.
+type downloadKey struct{}
+
+// isDownload reports whether req fetches a download, whose body may be
+// too large to hold in memory and whose URL carries a signature.
+func isDownload(req *http.Request) bool {
+	return req.Context().Value(downloadKey{}) != nil
+}
+
+// loggedURL is req's URL as logs and traces show it: without userinfo, and
+// for downloads without the query that signs the link.
+func loggedURL(req *http.Request) string {
+	if !isDownload(req) {
+		return req.URL.Redacted()
+	}
+	u := *req.URL
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+	return u.String()
+}
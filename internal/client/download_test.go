@@ -0,0 +1,204 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var exportFile = []byte(strings.Repeat("id,email\n1,a@example.com\n", 1000))
+
+// fileServer serves exportFile with Range support. While drops is
+// positive, a response is cut off after half its body.
+func fileServer(t *testing.T, drops int) (*Client, *[]string) {
+	t.Helper()
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Error("credentials sent to the download URL")
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		if drops > 0 && r.Header.Get("Range") == "" {
+			drops--
+			w.Header().Set("Content-Length", strconv.Itoa(len(exportFile)))
+			w.Write(exportFile[:len(exportFile)/2])
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "export.csv", time.Time{}, bytes.NewReader(exportFile))
+	}))
+	t.Cleanup(srv.Close)
+	return &Client{BaseURL: srv.URL, Token: "tok", HTTPClient: srv.Client(), MaxRetries: 2}, &ranges
+}
+
+func TestDownload(t *testing.T) {
+	c, _ := fileServer(t, 0)
+	path := filepath.Join(t.TempDir(), "export.csv")
+	var done, total int64
+	err := c.Download(context.Background(), Download{
+		URL:      c.BaseURL + "/file",
+		Path:     path,
+		Progress: func(d, t int64) { done, total = d, t },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, exportFile) {
+		t.Fatalf("downloaded %d bytes, want %d", len(got), len(exportFile))
+	}
+	if done != int64(len(exportFile)) || total != done {
+		t.Fatalf("progress = %d/%d", done, total)
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Fatal("part file left behind")
+	}
+}
+
+func TestDownloadTracedAndRecorded(t *testing.T) {
+	c, _ := fileServer(t, 0)
+	var log bytes.Buffer
+	dir := t.TempDir()
+	spans, err := OpenSpanFile(filepath.Join(dir, "spans.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette := filepath.Join(dir, "cassette")
+	c.WrapTransport(func(base http.RoundTripper) http.RoundTripper {
+		return &Recorder{Base: base, Dir: cassette}
+	})
+	c.Trace(&Tracer{Log: &log, Bodies: true, Spans: spans})
+
+	path := filepath.Join(dir, "export.csv")
+	if err := c.Download(context.Background(), Download{URL: c.BaseURL + "/file?sig=secret", Path: path}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, exportFile) {
+		t.Fatalf("downloaded %d bytes, want %d", len(got), len(exportFile))
+	}
+	if err := spans.Close("test", nil); err != nil {
+		t.Fatal(err)
+	}
+	traced, _ := os.ReadFile(filepath.Join(dir, "spans.jsonl"))
+	if strings.Contains(log.String(), "secret") || strings.Contains(string(traced), "secret") {
+		t.Fatalf("signature logged:\n%s\n%s", log.String(), traced)
+	}
+	if !strings.Contains(log.String(), "> GET "+c.BaseURL+"/file\n") || strings.Contains(log.String(), "1,a@example.com") {
+		t.Fatalf("log:\n%s", log.String())
+	}
+	if entries, _ := os.ReadDir(cassette); len(entries) != 0 {
+		t.Fatalf("download recorded: %v", entries)
+	}
+}
+
+func TestDownloadResumesPartFile(t *testing.T) {
+	c, ranges := fileServer(t, 0)
+	path := filepath.Join(t.TempDir(), "export.csv")
+	if err := os.WriteFile(path+".part", exportFile[:100], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Download(context.Background(), Download{
+		URL:  c.BaseURL + "/file",
+		Path: path,
+		Transform: func(dst io.Writer, src io.Reader) error {
+			data, err := io.ReadAll(src)
+			_, werr := dst.Write(bytes.ToUpper(data))
+			return errors.Join(err, werr)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*ranges) != 1 || (*ranges)[0] != "bytes=100-" {
+		t.Fatalf("ranges = %q", *ranges)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, bytes.ToUpper(exportFile)) {
+		t.Fatal("transformed output does not match the whole file")
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Fatal("part file left behind")
+	}
+}
+
+func TestDownloadReconnects(t *testing.T) {
+	stubSleep(t)
+	c, ranges := fileServer(t, 1)
+	path := filepath.Join(t.TempDir(), "export.csv")
+	if err := c.Download(context.Background(), Download{URL: c.BaseURL + "/file", Path: path}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*ranges) != 2 || !strings.HasPrefix((*ranges)[1], "bytes=") {
+		t.Fatalf("ranges = %q", *ranges)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, exportFile) {
+		t.Fatalf("downloaded %d bytes, want %d", len(got), len(exportFile))
+	}
+}
+
+func TestDownloadIncomplete(t *testing.T) {
+	stubSleep(t)
+	c, _ := fileServer(t, 1)
+	c.MaxRetries = 0
+	path := filepath.Join(t.TempDir(), "export.csv")
+	err := c.Download(context.Background(), Download{URL: c.BaseURL + "/file", Path: path})
+	if !errors.Is(err, ErrIncompleteDownload) {
+		t.Fatalf("err = %v, want ErrIncompleteDownload", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("incomplete download left at the output path")
+	}
+
+	// Running it again picks up where the part file ends.
+	if err := c.Download(context.Background(), Download{URL: c.BaseURL + "/file", Path: path}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, exportFile) {
+		t.Fatalf("downloaded %d bytes, want %d", len(got), len(exportFile))
+	}
+}
+
+func TestDownloadAlreadyComplete(t *testing.T) {
+	c, _ := fileServer(t, 0)
+	path := filepath.Join(t.TempDir(), "export.csv")
+	if err := os.WriteFile(path+".part", exportFile, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Download(context.Background(), Download{URL: c.BaseURL + "/file", Path: path}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if !bytes.Equal(got, exportFile) {
+		t.Fatalf("got %d bytes", len(got))
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		in           string
+		start, total int64
+		ok           bool
+	}{
+		{"bytes 100-199/1000", 100, 1000, true},
+		{"bytes 0-9/*", 0, -1, true},
+		{"bytes */1000", 0, 1000, true},
+		{"items 0-9/10", 0, 0, false},
+		{"bytes x-9/10", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, total, ok := parseContentRange(tt.in)
+		if start != tt.start || total != tt.total || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", tt.in, start, total, ok)
+		}
+	}
+}
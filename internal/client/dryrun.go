@@ -0,0 +1,180 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDryRun is returned in place of a response when a request was printed
+// by DryRun instead of being sent.
+var ErrDryRun = errors.New("dry run: request not sent")
+
+// Dry-run output formats.
+const (
+	DryRunText = "text"
+	DryRunCurl = "curl"
+	DryRunHAR  = "har"
+)
+
+// DryRun describes requests instead of sending them. Only methods with
+// side effects are intercepted; GET and HEAD still go out so commands can
+// look things up before they would mutate. Requests from concurrent calls
+// are written whole, one at a time.
+type DryRun struct {
+	Out    io.Writer
+	Format string
+
+	mu sync.Mutex
+}
+
+func (d *DryRun) intercepts(method string) bool {
+	return d != nil && method != http.MethodGet && method != http.MethodHead
+}
+
+func (d *DryRun) write(req *http.Request, body []byte) error {
+	headers := redactedHeaders(req.Header)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch d.Format {
+	case DryRunCurl:
+		return writeCurl(d.Out, req, headers, body)
+	case DryRunHAR:
+		return writeHAR(d.Out, req, headers, body)
+	}
+	return writeText(d.Out, req, headers, body)
+}
+
+type header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// redactedHeaders lists headers in a stable order with credentials
+// replaced; the auth scheme is kept so the output still shows which
+// credential type would be sent.
+func redactedHeaders(h http.Header) []header {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var out []header
+	for _, name := range names {
+		for _, v := range h[name] {
+			out = append(out, header{name, redactHeader(name, v)})
+		}
+	}
+	return out
+}
+
+func redactHeader(name, value string) string {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization":
+		if scheme, _, ok := strings.Cut(value, " "); ok {
+			return scheme + " <redacted>"
+		}
+		return "<redacted>"
+	case "Cookie", "X-Api-Key":
+		return "<redacted>"
+	}
+	return value
+}
+
+func writeText(w io.Writer, req *http.Request, headers []header, body []byte) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s\n", req.Method, req.URL)
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\n", h.Name, h.Value)
+	}
+	if len(body) > 0 {
+		buf.WriteByte('\n')
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") == nil {
+			body = pretty.Bytes()
+		}
+		buf.Write(body)
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeCurl(w io.Writer, req *http.Request, headers []header, body []byte) error {
+	parts := []string{"curl -X " + req.Method + " " + shellQuote(req.URL.String())}
+	for _, h := range headers {
+		parts = append(parts, "-H "+shellQuote(h.Name+": "+h.Value))
+	}
+	if len(body) > 0 {
+		parts = append(parts, "--data-raw "+shellQuote(string(body)))
+	}
+	_, err := fmt.Fprintln(w, strings.Join(parts, " \\\n  "))
+	return err
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeHAR prints a single-entry HAR 1.2 log. The response is empty since
+// nothing was sent.
+func writeHAR(w io.Writer, req *http.Request, headers []header, body []byte) error {
+	query := []header{}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			query = append(query, header{name, v})
+		}
+	}
+	sort.Slice(query, func(i, j int) bool { return query[i].Name < query[j].Name })
+	if headers == nil {
+		headers = []header{}
+	}
+
+	request := map[string]any{
+		"method":      req.Method,
+		"url":         req.URL.String(),
+		"httpVersion": "HTTP/1.1",
+		"cookies":     []any{},
+		"headers":     headers,
+		"queryString": query,
+		"headersSize": -1,
+		"bodySize":    len(body),
+	}
+	if len(body) > 0 {
+		request["postData"] = map[string]any{
+			"mimeType": req.Header.Get("Content-Type"),
+			"text":     string(body),
+		}
+	}
+	har := map[string]any{
+		"log": map[string]any{
+			"version": "1.2",
+			"creator": map[string]any{"name": "cio", "version": "dry-run"},
+			"entries": []any{map[string]any{
+				"startedDateTime": time.Now().UTC().Format(time.RFC3339Nano),
+				"time":            0,
+				"request":         request,
+				"response": map[string]any{
+					"status": 0, "statusText": "", "httpVersion": "", "cookies": []any{}, "headers": []any{},
+					"content":     map[string]any{"size": 0, "mimeType": ""},
+					"redirectURL": "", "headersSize": -1, "bodySize": -1,
+				},
+				"cache":   map[string]any{},
+				"timings": map[string]any{"send": 0, "wait": 0, "receive": 0},
+			}},
+		},
+	}
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func dryRunClient(t *testing.T, format string) (*Client, *bytes.Buffer, *int) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	var out bytes.Buffer
+	c := &Client{
+		BaseURL:    srv.URL,
+		Token:      "secret",
+		HTTPClient: srv.Client(),
+		DryRun:     &DryRun{Out: &out, Format: format},
+	}
+	return c, &out, &calls
+}
+
+func TestDryRunText(t *testing.T) {
+	c, out, calls := dryRunClient(t, DryRunText)
+	_, err := c.Post(context.Background(), "/v1/send/sms", map[string]string{"to": "+1555"})
+	if !errors.Is(err, ErrDryRun) {
+		t.Fatalf("got %v, want ErrDryRun", err)
+	}
+	if *calls != 0 {
+		t.Fatalf("request was sent")
+	}
+	want := "POST " + c.BaseURL + "/v1/send/sms\n" +
+		"Authorization: Bearer <redacted>\n" +
+		"Content-Type: application/json\n\n" +
+		"{\n  \"to\": \"+1555\"\n}\n"
+	if out.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestDryRunLetsReadsThrough(t *testing.T) {
+	c, out, calls := dryRunClient(t, DryRunText)
+	if _, err := c.Get(context.Background(), "/v1/segments", nil); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 1 || out.Len() != 0 {
+		t.Fatalf("calls=%d output=%q", *calls, out)
+	}
+}
+
+func TestDryRunCurl(t *testing.T) {
+	c, out, _ := dryRunClient(t, DryRunCurl)
+	c.Username = "site"
+	_, err := c.Post(context.Background(), "/api/v2/entity", map[string]string{"name": "it's"})
+	if !errors.Is(err, ErrDryRun) {
+		t.Fatalf("got %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		"curl -X POST '" + c.BaseURL + "/api/v2/entity'",
+		"-H 'Authorization: Basic <redacted>'",
+		`--data-raw '{"name":"it'\''s"}'`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "secret") {
+		t.Fatalf("credentials leaked: %s", got)
+	}
+}
+
+func TestDryRunHAR(t *testing.T) {
+	c, out, _ := dryRunClient(t, DryRunHAR)
+	_, err := c.Delete(context.Background(), "/v1/segments/3", url.Values{"force": {"true"}})
+	if !errors.Is(err, ErrDryRun) {
+		t.Fatalf("got %v", err)
+	}
+	var har struct {
+		Log struct {
+			Version string
+			Entries []struct {
+				Request struct {
+					Method      string
+					URL         string
+					Headers     []header
+					QueryString []header
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &har); err != nil {
+		t.Fatalf("invalid HAR: %v\n%s", err, out)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 1 {
+		t.Fatalf("unexpected log: %+v", har.Log)
+	}
+	req := har.Log.Entries[0].Request
+	if req.Method != "DELETE" || req.URL != c.BaseURL+"/v1/segments/3?force=true" {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	if len(req.QueryString) != 1 || req.QueryString[0] != (header{"force", "true"}) {
+		t.Fatalf("query: %+v", req.QueryString)
+	}
+	for _, h := range req.Headers {
+		if h.Name == "Authorization" && h.Value != "Bearer <redacted>" {
+			t.Fatalf("authorization not redacted: %s", h.Value)
+		}
+	}
+}
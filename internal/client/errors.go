@@ -0,0 +1,77 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError is returned for any non-2xx response from Customer.io.
+type APIError struct {
+	StatusCode int           `json:"status"`
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	RequestID  string        `json:"request_id,omitempty"`
+	Errors     []ErrorDetail `json:"errors,omitempty"`
+	Body       string        `json:"-"`
+}
+
+// ErrorDetail is one entry of the "errors" array in a Customer.io error
+// response.
+type ErrorDetail struct {
+	Detail string         `json:"detail,omitempty"`
+	Status string         `json:"status,omitempty"`
+	Source map[string]any `json:"source,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Body
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, msg)
+}
+
+// Message is the most specific human-readable explanation available.
+func (e *APIError) Message() string {
+	var details []string
+	for _, d := range e.Errors {
+		if d.Detail != "" {
+			details = append(details, d.Detail)
+		}
+	}
+	if len(details) > 0 {
+		return strings.Join(details, "; ")
+	}
+	return e.Error()
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		RequestID:  resp.Header.Get("X-Request-Id"),
+	}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.Path = resp.Request.URL.Path
+	}
+	if e.Body == "" {
+		e.Body = resp.Status
+	}
+
+	var parsed struct {
+		Errors []ErrorDetail `json:"errors"`
+		Meta   struct {
+			Error string `json:"error"`
+		} `json:"meta"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		e.Errors = parsed.Errors
+		if parsed.Meta.Error != "" {
+			e.Errors = append(e.Errors, ErrorDetail{Detail: parsed.Meta.Error})
+		}
+	}
+	return e
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"errors":[{"detail":"name is required","status":"422","source":{"pointer":"/segment/name"}}]}`))
+	})
+
+	_, err := c.Post(context.Background(), "/v1/segments", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %T: %v", err, err)
+	}
+	if apiErr.StatusCode != 422 || apiErr.Method != http.MethodPost || apiErr.Path != "/v1/segments" {
+		t.Fatalf("got %+v", apiErr)
+	}
+	if apiErr.RequestID != "req-123" {
+		t.Fatalf("request id = %q", apiErr.RequestID)
+	}
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0].Source["pointer"] != "/segment/name" {
+		t.Fatalf("errors = %+v", apiErr.Errors)
+	}
+	if apiErr.Message() != "name is required" {
+		t.Fatalf("message = %q", apiErr.Message())
+	}
+}
+
+func TestAPIErrorMeta(t *testing.T) {
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"meta":{"error":"segment not found"}}`))
+	})
+
+	_, err := c.Get(context.Background(), "/v1/segments/9", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %T", err)
+	}
+	if apiErr.Message() != "segment not found" {
+		t.Fatalf("message = %q", apiErr.Message())
+	}
+}
+
+func TestAPIErrorPlainBody(t *testing.T) {
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("upstream down"))
+	})
+
+	_, err := c.Get(context.Background(), "/v1/test", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %T", err)
+	}
+	if len(apiErr.Errors) != 0 {
+		t.Fatalf("errors = %+v", apiErr.Errors)
+	}
+	if apiErr.Message() != "HTTP 502: upstream down" {
+		t.Fatalf("message = %q", apiErr.Message())
+	}
+}
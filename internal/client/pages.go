@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/url"
+)
+
+// Pages iterates over a cursor-paginated GET endpoint. Each page's "next"
+// cursor is passed back as the "start" query parameter until the API stops
+// returning one. Iteration ends after the first error.
+func (c *Client) Pages(ctx context.Context, path string, query url.Values) iter.Seq2[json.RawMessage, error] {
+	return func(yield func(json.RawMessage, error) bool) {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = append([]string(nil), v...)
+		}
+		for {
+			data, err := c.Get(ctx, path, q)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(data, nil) {
+				return
+			}
+			next := NextCursor(data)
+			if next == "" || next == q.Get("start") {
+				return
+			}
+			q.Set("start", next)
+		}
+	}
+}
+
+// NextCursor extracts the "next" cursor from a list response. Customer.io
+// returns it as a string, but numbers are tolerated too.
+func NextCursor(data json.RawMessage) string {
+	var page struct {
+		Next json.RawMessage `json:"next"`
+	}
+	if err := json.Unmarshal(data, &page); err != nil || len(page.Next) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(page.Next, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(page.Next, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
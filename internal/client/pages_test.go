@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestPages(t *testing.T) {
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("limit") != "2" {
+			t.Errorf("limit = %s", r.URL.Query().Get("limit"))
+		}
+		switch r.URL.Query().Get("start") {
+		case "":
+			_, _ = w.Write([]byte(`{"messages":[1,2],"next":"p2"}`))
+		case "p2":
+			_, _ = w.Write([]byte(`{"messages":[3,4],"next":"p3"}`))
+		case "p3":
+			_, _ = w.Write([]byte(`{"messages":[5],"next":""}`))
+		default:
+			t.Errorf("unexpected start %s", r.URL.Query().Get("start"))
+		}
+	})
+
+	var pages []string
+	for data, err := range c.Pages(context.Background(), "/v1/messages", url.Values{"limit": {"2"}}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, string(data))
+	}
+	if len(pages) != 3 {
+		t.Fatalf("got %d pages: %v", len(pages), pages)
+	}
+}
+
+func TestPagesStopsEarly(t *testing.T) {
+	calls := 0
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"messages":[1],"next":"more"}`))
+	})
+
+	for range c.Pages(context.Background(), "/v1/messages", nil) {
+		break
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d", calls)
+	}
+}
+
+func TestPagesRepeatedCursor(t *testing.T) {
+	calls := 0
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"messages":[1],"next":"same"}`))
+	})
+
+	for _, err := range c.Pages(context.Background(), "/v1/messages", nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d", calls)
+	}
+}
+
+func TestPagesError(t *testing.T) {
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	var gotErr error
+	for _, err := range c.Pages(context.Background(), "/v1/messages", nil) {
+		gotErr = err
+	}
+	if gotErr == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	cases := map[string]string{
+		`{"next":"abc"}`: "abc",
+		`{"next":""}`:    "",
+		`{"next":null}`:  "",
+		`{"next":42}`:    "42",
+		`{}`:             "",
+		`[1,2]`:          "",
+	}
+	for in, want := range cases {
+		if got := NextCursor(json.RawMessage(in)); got != want {
+			t.Errorf("%s: got %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/leechael/cio/internal/auth"
+	"github.com/leechael/cio/internal/config"
+)
+
+// NewPipelines builds a Pipelines (CDP) API client from a profile. The
+// write key is sent as the HTTP Basic username with an empty password.
+// $CIO_CDP_BASE_URL overrides the region default.
+func NewPipelines(p *config.Profile) (*Client, error) {
+	key, err := auth.ResolveWriteKey(p)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := os.Getenv("CIO_CDP_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://cdp.customer.io"
+		if p.Region == "eu" {
+			baseURL = "https://cdp-eu.customer.io"
+		}
+	}
+
+	return &Client{
+		BaseURL:      baseURL,
+		Username:     key.Value,
+		TokenSource:  key.String(),
+		HTTPClient:   &http.Client{},
+		MaxRetries:   DefaultMaxRetries,
+		RetryMaxWait: DefaultRetryMaxWait,
+		Limiter:      NewLimiter(nil),
+		RateClass:    ClassPipelines,
+		Timeout:      DefaultTimeout,
+	}, nil
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/leechael/cio/internal/config"
+)
+
+func TestNewPipelines(t *testing.T) {
+	t.Run("missing write key", func(t *testing.T) {
+		t.Setenv("CUSTOMERIO_WRITE_KEY", "")
+		if _, err := NewPipelines(&config.Profile{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("eu region", func(t *testing.T) {
+		t.Setenv("CUSTOMERIO_WRITE_KEY", "wk")
+		c, err := NewPipelines(&config.Profile{Region: "eu"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.BaseURL != "https://cdp-eu.customer.io" || c.Username != "wk" || c.Token != "" {
+			t.Fatalf("got %+v", c)
+		}
+	})
+}
+
+func TestRateClassOverridesPath(t *testing.T) {
+	stubClock(t)
+	waits := stubSleep(t)
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "wk" || pass != "" {
+			t.Errorf("auth = %q %q", user, pass)
+		}
+	})
+	c.Username = "wk"
+	c.Token = ""
+	c.RateClass = ClassPipelines
+	c.Limiter = NewLimiter(map[string]Rate{ClassPipelines: {Requests: 1, Per: time.Second}})
+
+	// /v1/track would be "general" by path; RateClass must win.
+	for i := 0; i < 2; i++ {
+		if _, err := c.Post(context.Background(), "/v1/track", map[string]any{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*waits) != 1 || (*waits)[0] != time.Second {
+		t.Fatalf("waits = %v", *waits)
+	}
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint classes with their own Customer.io rate limit.
+const (
+	ClassGeneral       = "general"
+	ClassTransactional = "transactional"
+	ClassBroadcast     = "broadcast"
+	ClassTrack         = "track"
+	ClassPipelines     = "pipelines"
+)
+
+// Rate allows Requests requests every Per. A zero Rate means unlimited.
+type Rate struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (r Rate) String() string {
+	if r.Requests <= 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", r.Requests, r.Per)
+}
+
+var DefaultRateLimits = map[string]Rate{
+	ClassGeneral:       {Requests: 10, Per: time.Second},
+	ClassTransactional: {Requests: 100, Per: time.Second},
+	ClassBroadcast:     {Requests: 1, Per: 10 * time.Second},
+	ClassTrack:         {Requests: 30, Per: time.Second},
+	ClassPipelines:     {Requests: 100, Per: time.Second},
+}
+
+var broadcastTriggerPath = regexp.MustCompile(`^/v1/campaigns/[^/]+/triggers$`)
+
+func endpointClass(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/"):
+		return ClassTrack
+	case strings.HasPrefix(path, "/v1/send/"):
+		return ClassTransactional
+	case broadcastTriggerPath.MatchString(path):
+		return ClassBroadcast
+	}
+	return ClassGeneral
+}
+
+var now = time.Now
+
+// Limiter is a set of token buckets, one per endpoint class. It is safe for
+// concurrent use so fan-out commands can share a single client.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	rate   Rate
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter builds a limiter from DefaultRateLimits with the given
+// per-class overrides applied.
+func NewLimiter(overrides map[string]Rate) *Limiter {
+	l := &Limiter{buckets: make(map[string]*bucket)}
+	for class, r := range DefaultRateLimits {
+		l.buckets[class] = newBucket(r)
+	}
+	for class, r := range overrides {
+		l.buckets[class] = newBucket(r)
+	}
+	return l
+}
+
+func newBucket(r Rate) *bucket {
+	return &bucket{rate: r, tokens: float64(r.Requests), last: now()}
+}
+
+// Wait blocks until a request of the given class may be sent or ctx is
+// done.
+func (l *Limiter) Wait(ctx context.Context, class string) error {
+	if d := l.reserve(class); d > 0 {
+		return sleep(ctx, d)
+	}
+	return nil
+}
+
+// reserve takes a token from the class bucket, letting it go negative, and
+// returns how long the caller must wait for that token to be earned.
+func (l *Limiter) reserve(class string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[class]
+	if !ok {
+		b = l.buckets[ClassGeneral]
+	}
+	if b == nil || b.rate.Requests <= 0 || b.rate.Per <= 0 {
+		return 0
+	}
+
+	t := now()
+	perToken := float64(b.rate.Per) / float64(b.rate.Requests)
+	b.tokens += float64(t.Sub(b.last)) / perToken
+	if burst := float64(b.rate.Requests); b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = t
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens * perToken)
+}
+
+// ParseRate parses "N/duration" such as "10/s", "1/10s" or "100/1m".
+// "off" and "0" disable limiting for the class.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" || s == "0" {
+		return Rate{}, nil
+	}
+	n, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q (want N/duration, e.g. 10/s)", s)
+	}
+	requests, err := strconv.Atoi(n)
+	if err != nil || requests < 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: bad request count", s)
+	}
+	if per != "" && (per[0] < '0' || per[0] > '9') {
+		per = "1" + per
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: bad duration", s)
+	}
+	return Rate{Requests: requests, Per: d}, nil
+}
+
+// ParseRateLimits parses class=rate pairs, rejecting unknown classes.
+func ParseRateLimits(pairs map[string]string) (map[string]Rate, error) {
+	limits := make(map[string]Rate, len(pairs))
+	for class, v := range pairs {
+		if _, ok := DefaultRateLimits[class]; !ok {
+			return nil, fmt.Errorf("unknown rate limit class %q (want %s)", class, rateClasses())
+		}
+		r, err := ParseRate(v)
+		if err != nil {
+			return nil, err
+		}
+		limits[class] = r
+	}
+	return limits, nil
+}
+
+// rateClasses lists the classes of DefaultRateLimits for error messages.
+func rateClasses() string {
+	classes := make([]string, 0, len(DefaultRateLimits))
+	for class := range DefaultRateLimits {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	last := len(classes) - 1
+	return strings.Join(classes[:last], ", ") + " or " + classes[last]
+}
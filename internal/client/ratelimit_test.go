@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func stubClock(t *testing.T) *time.Time {
+	t.Helper()
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	orig := now
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = orig })
+	return &clock
+}
+
+func TestEndpointClass(t *testing.T) {
+	cases := map[string]string{
+		"/v1/segments":              ClassGeneral,
+		"/v1/send/email":            ClassTransactional,
+		"/v1/send/sms":              ClassTransactional,
+		"/v1/campaigns/7/triggers":  ClassBroadcast,
+		"/v1/campaigns/7/actions":   ClassGeneral,
+		"/v1/broadcasts/7/triggers": ClassGeneral,
+		"/api/v2/entity":            ClassTrack,
+	}
+	for path, want := range cases {
+		if got := endpointClass(path); got != want {
+			t.Errorf("%s: got %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestLimiterBurstThenWait(t *testing.T) {
+	stubClock(t)
+	l := NewLimiter(map[string]Rate{ClassGeneral: {Requests: 2, Per: time.Second}})
+
+	if d := l.reserve(ClassGeneral); d != 0 {
+		t.Fatalf("first: %v", d)
+	}
+	if d := l.reserve(ClassGeneral); d != 0 {
+		t.Fatalf("second: %v", d)
+	}
+	if d := l.reserve(ClassGeneral); d != 500*time.Millisecond {
+		t.Fatalf("third: %v", d)
+	}
+	if d := l.reserve(ClassGeneral); d != time.Second {
+		t.Fatalf("fourth: %v", d)
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	clock := stubClock(t)
+	l := NewLimiter(nil)
+
+	if d := l.reserve(ClassBroadcast); d != 0 {
+		t.Fatalf("first: %v", d)
+	}
+	if d := l.reserve(ClassBroadcast); d != 10*time.Second {
+		t.Fatalf("second: %v", d)
+	}
+	*clock = clock.Add(20 * time.Second)
+	if d := l.reserve(ClassBroadcast); d != 0 {
+		t.Fatalf("after refill: %v", d)
+	}
+}
+
+func TestLimiterClassesIndependent(t *testing.T) {
+	stubClock(t)
+	l := NewLimiter(nil)
+
+	l.reserve(ClassBroadcast)
+	if d := l.reserve(ClassGeneral); d != 0 {
+		t.Fatalf("general throttled by broadcast: %v", d)
+	}
+}
+
+func TestLimiterOff(t *testing.T) {
+	stubClock(t)
+	l := NewLimiter(map[string]Rate{ClassBroadcast: {}})
+	for i := 0; i < 5; i++ {
+		if d := l.reserve(ClassBroadcast); d != 0 {
+			t.Fatalf("call %d: %v", i, d)
+		}
+	}
+}
+
+func TestClientUsesLimiter(t *testing.T) {
+	stubClock(t)
+	waits := stubSleep(t)
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+	c.Limiter = NewLimiter(nil)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Post(context.Background(), "/v1/campaigns/1/triggers", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*waits) != 1 || (*waits)[0] != 10*time.Second {
+		t.Fatalf("waits = %v", *waits)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Rate
+	}{
+		{"10/s", Rate{10, time.Second}},
+		{"1/10s", Rate{1, 10 * time.Second}},
+		{"100/1m", Rate{100, time.Minute}},
+		{"off", Rate{}},
+		{"0", Rate{}},
+	}
+	for _, tc := range cases {
+		got, err := ParseRate(tc.in)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.in, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.in, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"10", "x/s", "10/x", "-1/s", "1/0s"} {
+		if _, err := ParseRate(bad); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits(map[string]string{"general": "5/s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits[ClassGeneral] != (Rate{5, time.Second}) {
+		t.Fatalf("got %v", limits)
+	}
+
+	_, err = ParseRateLimits(map[string]string{"bogus": "5/s"})
+	want := `unknown rate limit class "bogus" (want broadcast, general, pipelines, track or transactional)`
+	if err == nil || err.Error() != want {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	stubClock(t)
+	l := NewLimiter(nil)
+	l.reserve(ClassBroadcast)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx, ClassBroadcast); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// readOnlySafe lists the non-GET requests allowed in read-only mode: POST
+// searches that only read data.
+var readOnlySafe = map[string]bool{
+	"POST /v1/customers":              true, // customers search
+	"POST /v1/objects":                true, // objects search
+	"POST /v1/esp_suppression/search": true,
+}
+
+// ReadOnlyError is returned when read-only mode refuses a request.
+type ReadOnlyError struct {
+	Method string
+	Path   string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("read-only mode: refusing %s %s (only reads and searches are allowed)", e.Method, e.Path)
+}
+
+// allowedReadOnly reports whether a request may be sent in read-only mode.
+func allowedReadOnly(method, path string) bool {
+	return method == http.MethodGet || method == http.MethodHead || readOnlySafe[method+" "+path]
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client(), ReadOnly: true}
+	ctx := context.Background()
+
+	_, err := c.Delete(ctx, "/v1/segments/1", nil)
+	var roErr *ReadOnlyError
+	if !errors.As(err, &roErr) || roErr.Method != "DELETE" || roErr.Path != "/v1/segments/1" {
+		t.Fatalf("got %v, want ReadOnlyError", err)
+	}
+	if _, err := c.Post(ctx, "/v1/send/email", map[string]string{}); !errors.As(err, &roErr) {
+		t.Fatalf("got %v, want ReadOnlyError", err)
+	}
+
+	if _, err := c.Get(ctx, "/v1/segments", nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/v1/customers", "/v1/objects", "/v1/esp_suppression/search"} {
+		if _, err := c.Post(ctx, path, map[string]string{}); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+	}
+	if len(sent) != 4 {
+		t.Fatalf("sent %v", sent)
+	}
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultMaxRetries   = 3
+	DefaultRetryMaxWait = 30 * time.Second
+
+	retryBaseWait = 500 * time.Millisecond
+)
+
+// sleep waits for d or until ctx is done.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// shouldRetry reports whether a request may be sent again after the given
+// outcome. Idempotent methods are retried on 429, 5xx and any transport
+// error. Other methods (POST) are only retried when the request provably
+// never reached the server: a failed connect, or an explicit 429 rejection.
+// A replay miss is final.
+func shouldRetry(method string, resp *http.Response, err error) bool {
+	if errors.Is(err, ErrNotRecorded) {
+		return false
+	}
+	if resp == nil {
+		return err != nil && (isIdempotent(method) || isConnectError(err))
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(method)
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryWait returns how long to wait before the next attempt. The second
+// result is false when the server asked for a longer pause than maxWait
+// allows, in which case retrying is pointless.
+func retryWait(attempt int, resp *http.Response, maxWait time.Duration) (time.Duration, bool) {
+	if maxWait <= 0 {
+		maxWait = DefaultRetryMaxWait
+	}
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if d > maxWait {
+				return 0, false
+			}
+			return d, true
+		}
+	}
+	ceil := retryBaseWait << attempt
+	if ceil <= 0 || ceil > maxWait {
+		ceil = maxWait
+	}
+	return time.Duration(rand.Int64N(int64(ceil)) + 1), true
+}
+
+// parseRetryAfter accepts both forms allowed by RFC 9110: delay-seconds and
+// an HTTP-date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	d := t.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
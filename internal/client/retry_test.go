@@ -0,0 +1,250 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func stubSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	orig := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleep = orig })
+	return &waits
+}
+
+func retryServer(t *testing.T, maxRetries int, handler http.HandlerFunc) *Client {
+	t.Helper()
+	c := testServer(t, handler)
+	c.MaxRetries = maxRetries
+	c.RetryMaxWait = time.Second
+	return c
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	waits := stubSleep(t)
+	var calls int32
+	c := retryServer(t, 3, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+
+	data, err := c.Get(context.Background(), "/v1/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"ok":true}` {
+		t.Fatalf("got %s", data)
+	}
+	if calls != 3 {
+		t.Fatalf("calls = %d", calls)
+	}
+	if len(*waits) != 2 {
+		t.Fatalf("waits = %v", *waits)
+	}
+	for _, w := range *waits {
+		if w <= 0 || w > time.Second {
+			t.Fatalf("wait out of range: %v", w)
+		}
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	stubSleep(t)
+	var calls int32
+	c := retryServer(t, 2, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := c.Get(context.Background(), "/v1/test", nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 3 {
+		t.Fatalf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRetryDisabledByDefault(t *testing.T) {
+	stubSleep(t)
+	var calls int32
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	if _, err := c.Get(context.Background(), "/v1/test", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d", calls)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	waits := stubSleep(t)
+	var calls int32
+	c := retryServer(t, 3, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	if _, err := c.Get(context.Background(), "/v1/test", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(*waits) != 1 || (*waits)[0] != time.Second {
+		t.Fatalf("waits = %v", *waits)
+	}
+}
+
+func TestRetryAfterExceedsMaxWait(t *testing.T) {
+	waits := stubSleep(t)
+	var calls int32
+	c := retryServer(t, 3, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	if _, err := c.Get(context.Background(), "/v1/test", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 || len(*waits) != 0 {
+		t.Fatalf("calls = %d, waits = %v", calls, *waits)
+	}
+}
+
+func TestRetryPostBody(t *testing.T) {
+	stubSleep(t)
+	var calls int32
+	c := retryServer(t, 3, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"a":1}` {
+			t.Errorf("body = %s", body)
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	if _, err := c.Post(context.Background(), "/v1/send/email", json.RawMessage(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d", calls)
+	}
+}
+
+func TestNoRetryPostOnServerError(t *testing.T) {
+	stubSleep(t)
+	var calls int32
+	c := retryServer(t, 3, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	if _, err := c.Post(context.Background(), "/v1/send/email", json.RawMessage(`{}`)); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d", calls)
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+
+	cases := []struct {
+		name   string
+		method string
+		status int
+		err    error
+		want   bool
+	}{
+		{"get 502", http.MethodGet, 502, nil, true},
+		{"get 404", http.MethodGet, 404, nil, false},
+		{"get 501", http.MethodGet, 501, nil, false},
+		{"get read error", http.MethodGet, 0, readErr, true},
+		{"put 503", http.MethodPut, 503, nil, true},
+		{"post 429", http.MethodPost, 429, nil, true},
+		{"post 500", http.MethodPost, 500, nil, false},
+		{"post dial error", http.MethodPost, 0, dialErr, true},
+		{"post read error", http.MethodPost, 0, readErr, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var resp *http.Response
+			if tc.err == nil {
+				resp = &http.Response{StatusCode: tc.status}
+			}
+			if got := shouldRetry(tc.method, resp, tc.err); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if d, ok := parseRetryAfter("5", now); !ok || d != 5*time.Second {
+		t.Fatalf("seconds: got %v, %v", d, ok)
+	}
+	date := now.Add(10 * time.Second).Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date, now); !ok || d != 10*time.Second {
+		t.Fatalf("http-date: got %v, %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("", now); ok {
+		t.Fatal("empty should not parse")
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Fatal("garbage should not parse")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestRetryConnectFailure(t *testing.T) {
+	stubSleep(t)
+	var calls int32
+	c := &Client{
+		BaseURL: "http://cio.invalid",
+		Token:   "t",
+		HTTPClient: &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+		})},
+		MaxRetries:   2,
+		RetryMaxWait: time.Second,
+	}
+	if _, err := c.Post(context.Background(), "/v1/send/email", json.RawMessage(`{}`)); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 3 {
+		t.Fatalf("calls = %d, want 3", calls)
+	}
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SpanFile writes OpenTelemetry spans as OTLP/JSON, one
+// ExportTraceServiceRequest per line, the format read by the collector's
+// otlpjsonfile receiver. Every HTTP attempt becomes a client span under a
+// root span for the whole command, written by Close.
+type SpanFile struct {
+	mu      sync.Mutex
+	f       *os.File
+	traceID string
+	rootID  string
+	start   time.Time
+}
+
+// OpenSpanFile appends spans to path.
+func OpenSpanFile(path string) (*SpanFile, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &SpanFile{f: f, traceID: randomID(16), rootID: randomID(8), start: time.Now()}, nil
+}
+
+// Close records the root span, named after the command, and closes the
+// file.
+func (s *SpanFile) Close(name string, err error) error {
+	span := otlpSpan{
+		TraceID: s.traceID,
+		SpanID:  s.rootID,
+		Name:    name,
+		Kind:    spanKindInternal,
+		Start:   unixNano(s.start),
+		End:     unixNano(time.Now()),
+		Status:  spanStatus(err),
+	}
+	werr := s.write(span)
+	if cerr := s.f.Close(); werr == nil {
+		werr = cerr
+	}
+	return werr
+}
+
+func (s *SpanFile) record(req *http.Request, resp *http.Response, err error, start, end time.Time) {
+	span := otlpSpan{
+		TraceID:  s.traceID,
+		SpanID:   randomID(8),
+		ParentID: s.rootID,
+		Name:     req.Method + " " + req.URL.Path,
+		Kind:     spanKindClient,
+		Start:    unixNano(start),
+		End:      unixNano(end),
+		Attributes: []otlpAttr{
+			stringAttr("http.request.method", req.Method),
+			stringAttr("url.full", loggedURL(req)),
+			stringAttr("server.address", req.URL.Hostname()),
+		},
+		Status: spanStatus(err),
+	}
+	if err != nil {
+		span.Attributes = append(span.Attributes, stringAttr("error.type", errorType(err)))
+	} else {
+		span.Attributes = append(span.Attributes, intAttr("http.response.status_code", resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			span.Status = otlpStatus{Code: statusError, Message: resp.Status}
+			span.Attributes = append(span.Attributes, stringAttr("error.type", strconv.Itoa(resp.StatusCode)))
+		}
+	}
+	_ = s.write(span)
+}
+
+func (s *SpanFile) write(span otlpSpan) error {
+	line, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []otlpAttr{stringAttr("service.name", "cio")},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]string{"name": "github.com/leechael/cio"},
+				"spans": []otlpSpan{span},
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(append(line, '\n'))
+	return err
+}
+
+// OTLP span kinds and status codes.
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+
+	statusOK    = 1
+	statusError = 2
+)
+
+type otlpSpan struct {
+	TraceID    string     `json:"traceId"`
+	SpanID     string     `json:"spanId"`
+	ParentID   string     `json:"parentSpanId,omitempty"`
+	Name       string     `json:"name"`
+	Kind       int        `json:"kind"`
+	Start      string     `json:"startTimeUnixNano"`
+	End        string     `json:"endTimeUnixNano"`
+	Attributes []otlpAttr `json:"attributes,omitempty"`
+	Status     otlpStatus `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttr struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func stringAttr(k, v string) otlpAttr {
+	return otlpAttr{k, map[string]any{"stringValue": v}}
+}
+
+// intAttr encodes the value as a string, as OTLP/JSON does for int64.
+func intAttr(k string, v int) otlpAttr {
+	return otlpAttr{k, map[string]any{"intValue": strconv.Itoa(v)}}
+}
+
+func spanStatus(err error) otlpStatus {
+	if err != nil {
+		return otlpStatus{Code: statusError, Message: err.Error()}
+	}
+	return otlpStatus{Code: statusOK}
+}
+
+func errorType(err error) string {
+	if os.IsTimeout(err) {
+		return "timeout"
+	}
+	return "transport"
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
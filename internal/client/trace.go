@@ -0,0 +1,176 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTraceBody caps how much of a body --trace prints.
+const maxTraceBody = 64 << 10
+
+// Tracer is an http.RoundTripper that logs every attempt and records it as
+// a span. Log receives the method, URL, status, latency and rate-limit
+// headers; with Bodies it also gets headers and bodies, with credentials
+// and sensitive JSON fields redacted.
+type Tracer struct {
+	Base   http.RoundTripper
+	Log    io.Writer
+	Bodies bool
+	Spans  *SpanFile
+
+	mu sync.Mutex
+}
+
+// Trace wraps c's transport with t.
+func (c *Client) Trace(t *Tracer) {
+	c.WrapTransport(func(base http.RoundTripper) http.RoundTripper {
+		t.Base = base
+		return t
+	})
+}
+
+func (t *Tracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "> %s %s\n", req.Method, loggedURL(req))
+	if t.Bodies {
+		for _, h := range redactedHeaders(req.Header) {
+			fmt.Fprintf(&buf, "> %s: %s\n", h.Name, h.Value)
+		}
+		if req.GetBody != nil {
+			if rc, err := req.GetBody(); err == nil {
+				body, _ := io.ReadAll(rc)
+				rc.Close()
+				writeTraceBody(&buf, body)
+			}
+		}
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		fmt.Fprintf(&buf, "< error after %s: %v\n", elapsed.Round(time.Millisecond), err)
+	} else {
+		fmt.Fprintf(&buf, "< %s in %s", resp.Status, elapsed.Round(time.Millisecond))
+		for _, h := range rateLimitHeaders(resp.Header) {
+			fmt.Fprintf(&buf, " %s=%s", h.Name, h.Value)
+		}
+		buf.WriteByte('\n')
+		if t.Bodies {
+			for _, h := range redactedHeaders(resp.Header) {
+				fmt.Fprintf(&buf, "< %s: %s\n", h.Name, h.Value)
+			}
+			if isDownload(req) {
+				// Reading a download here would hold all of it in memory.
+				buf.WriteString("(download body not shown)\n")
+			} else {
+				body, rerr := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				// A failed read is replayed after the data so the caller
+				// sees the same error.
+				var rest io.Reader = bytes.NewReader(body)
+				if rerr != nil {
+					rest = io.MultiReader(rest, errReader{rerr})
+				}
+				resp.Body = io.NopCloser(rest)
+				writeTraceBody(&buf, body)
+			}
+		}
+	}
+
+	if t.Log != nil {
+		t.mu.Lock()
+		t.Log.Write(buf.Bytes())
+		t.mu.Unlock()
+	}
+	if t.Spans != nil {
+		t.Spans.record(req, resp, err, start, start.Add(elapsed))
+	}
+	return resp, err
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func rateLimitHeaders(h http.Header) []header {
+	var out []header
+	for name, values := range h {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "ratelimit") || strings.Contains(lower, "rate-limit") || lower == "retry-after" {
+			out = append(out, header{name, strings.Join(values, ",")})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func writeTraceBody(buf *bytes.Buffer, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	var v any
+	if json.Unmarshal(body, &v) == nil {
+		var out bytes.Buffer
+		enc := json.NewEncoder(&out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if enc.Encode(redactJSON(v)) == nil {
+			body = bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+		}
+	}
+	truncated := len(body) > maxTraceBody
+	if truncated {
+		body = body[:maxTraceBody]
+	}
+	buf.Write(body)
+	if truncated {
+		fmt.Fprintf(buf, "\n... (truncated at %d bytes)", maxTraceBody)
+	}
+	buf.WriteByte('\n')
+}
+
+// sensitiveKeys are substrings of JSON field names whose values are never
+// printed.
+var sensitiveKeys = []string{"password", "secret", "token", "api_key", "apikey", "authorization", "private_key", "write_key", "credential"}
+
+func redactJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			if sensitiveKey(k) {
+				v[k] = "<redacted>"
+			} else {
+				v[k] = redactJSON(x)
+			}
+		}
+	case []any:
+		for i, x := range v {
+			v[i] = redactJSON(x)
+		}
+	}
+	return v
+}
+
+func sensitiveKey(k string) bool {
+	k = strings.ToLower(k)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
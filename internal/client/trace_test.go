@@ -0,0 +1,127 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTracerVerbose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "9")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+	var log bytes.Buffer
+	c := &Client{BaseURL: srv.URL, Token: "secret-token", HTTPClient: srv.Client()}
+	c.Trace(&Tracer{Log: &log})
+
+	if _, err := c.Get(context.Background(), "/v1/segments", nil); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 2 || lines[0] != "> GET "+srv.URL+"/v1/segments" {
+		t.Fatalf("got:\n%s", log.String())
+	}
+	if !strings.HasPrefix(lines[1], "< 200 OK in ") || !strings.HasSuffix(lines[1], " X-Ratelimit-Remaining=9") {
+		t.Fatalf("got %q", lines[1])
+	}
+}
+
+func TestTracerBodiesRedacted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1,"api_key":"k-123"}`))
+	}))
+	defer srv.Close()
+	var log bytes.Buffer
+	c := &Client{BaseURL: srv.URL, Token: "secret-token", HTTPClient: srv.Client()}
+	c.Trace(&Tracer{Log: &log, Bodies: true})
+
+	data, err := c.Post(context.Background(), "/v1/webhooks", map[string]any{"name": "w", "auth": map[string]string{"password": "hunter2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"id":1,"api_key":"k-123"}` {
+		t.Fatalf("response body changed: %s", data)
+	}
+	got := log.String()
+	for _, leak := range []string{"secret-token", "hunter2", "k-123"} {
+		if strings.Contains(got, leak) {
+			t.Errorf("%q leaked:\n%s", leak, got)
+		}
+	}
+	for _, want := range []string{"> Authorization: Bearer <redacted>", `"password": "<redacted>"`, `"name": "w"`, `"api_key": "<redacted>"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestSpanFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	spans, err := OpenSpanFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client()}
+	c.Trace(&Tracer{Spans: spans})
+	if _, err := c.Get(context.Background(), "/v1/segments/9", nil); err == nil {
+		t.Fatal("expected 404")
+	}
+	if err := spans.Close("cio segments get", errors.New("HTTP 404")); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []otlpSpan
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct{ Spans []otlpSpan }
+			}
+		}
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, req.ResourceSpans[0].ScopeSpans[0].Spans...)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d spans", len(got))
+	}
+	call, root := got[0], got[1]
+	if call.Name != "GET /v1/segments/9" || call.Kind != spanKindClient || call.Status.Code != statusError {
+		t.Errorf("http span: %+v", call)
+	}
+	if root.Name != "cio segments get" || root.ParentID != "" || call.ParentID != root.SpanID || call.TraceID != root.TraceID {
+		t.Errorf("root span: %+v", root)
+	}
+	if len(root.TraceID) != 32 || len(root.SpanID) != 16 {
+		t.Errorf("bad IDs: %s %s", root.TraceID, root.SpanID)
+	}
+	found := false
+	for _, a := range call.Attributes {
+		if a.Key == "http.response.status_code" && a.Value["intValue"] == "404" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("status attribute missing: %+v", call.Attributes)
+	}
+}
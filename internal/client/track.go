@@ -0,0 +1,54 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/leechael/cio/internal/auth"
+	"github.com/leechael/cio/internal/config"
+)
+
+// NewTrack builds a Track API client from a profile. It shares the App API
+// client's retry, timeout and rate limit machinery but authenticates with
+// HTTP Basic (site ID and API key). $CIO_TRACK_BASE_URL overrides the
+// region default.
+func NewTrack(p *config.Profile) (*Client, error) {
+	creds, err := auth.ResolveTrack(p)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := os.Getenv("CIO_TRACK_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://track.customer.io"
+		if p.Region == "eu" {
+			baseURL = "https://track-eu.customer.io"
+		}
+	}
+
+	return &Client{
+		BaseURL:      baseURL,
+		Username:     creds.SiteID,
+		Token:        creds.Key.Value,
+		TokenSource:  creds.Key.String(),
+		HTTPClient:   &http.Client{},
+		MaxRetries:   DefaultMaxRetries,
+		RetryMaxWait: DefaultRetryMaxWait,
+		Limiter:      NewLimiter(nil),
+		Timeout:      DefaultTimeout,
+	}, nil
+}
+
+// Entity is one operation on the Track API's unified /api/v2/entity
+// endpoint. Type is "person" or "object"; Action is identify, event,
+// delete, suppress or unsuppress.
+type Entity struct {
+	Type        string            `json:"type"`
+	Identifiers map[string]string `json:"identifiers"`
+	Action      string            `json:"action"`
+	Name        string            `json:"name,omitempty"`
+	ID          string            `json:"id,omitempty"`
+	Timestamp   int64             `json:"timestamp,omitempty"`
+	Attributes  json.RawMessage   `json:"attributes,omitempty"`
+}
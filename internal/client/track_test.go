@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/leechael/cio/internal/config"
+)
+
+func TestNewTrack(t *testing.T) {
+	t.Run("missing site id", func(t *testing.T) {
+		t.Setenv("CUSTOMERIO_SITE_ID", "")
+		t.Setenv("CUSTOMERIO_API_KEY", "key")
+		if _, err := NewTrack(&config.Profile{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		t.Setenv("CUSTOMERIO_SITE_ID", "site")
+		t.Setenv("CUSTOMERIO_API_KEY", "")
+		if _, err := NewTrack(&config.Profile{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("eu region", func(t *testing.T) {
+		t.Setenv("CUSTOMERIO_SITE_ID", "")
+		t.Setenv("CUSTOMERIO_API_KEY", "key")
+		c, err := NewTrack(&config.Profile{Region: "eu", SiteID: "site"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.BaseURL != "https://track-eu.customer.io" || c.Username != "site" || c.Token != "key" {
+			t.Fatalf("got %+v", c)
+		}
+	})
+}
+
+func TestBasicAuth(t *testing.T) {
+	var got Entity
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "site" || pass != "key" {
+			t.Errorf("auth = %q %q %v", user, pass, ok)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, Username: "site", Token: "key", HTTPClient: srv.Client()}
+	e := &Entity{Type: "person", Action: "identify", Identifiers: map[string]string{"id": "42"}}
+	data, err := c.Post(context.Background(), "/api/v2/entity", e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{}" {
+		t.Fatalf("got %s", data)
+	}
+	if got.Type != "person" || got.Identifiers["id"] != "42" || got.Attributes != nil {
+		t.Fatalf("got %+v", got)
+	}
+}
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultProfile  = "default"
+	DefaultTokenEnv = "CUSTOMERIO_API_TOKEN"
+
+	// Track API credentials: a site ID and API key pair.
+	DefaultSiteIDEnv   = "CUSTOMERIO_SITE_ID"
+	DefaultTrackKeyEnv = "CUSTOMERIO_API_KEY"
+
+	// Pipelines (CDP) API write key.
+	DefaultWriteKeyEnv = "CUSTOMERIO_WRITE_KEY"
+)
+
+// Profile describes one Customer.io workspace: where to find its
+// credentials and which endpoints to talk to.
+type Profile struct {
+	Name string `json:"-"`
+
+	Region       string            `json:"region,omitempty"`
+	BaseURL      string            `json:"base_url,omitempty"`
+	TokenEnv     string            `json:"token_env,omitempty"`
+	TokenFile    string            `json:"token_file,omitempty"`
+	TokenCommand string            `json:"token_command,omitempty"`
+	RateLimits   map[string]string `json:"rate_limits,omitempty"`
+
+	SiteID          string `json:"site_id,omitempty"`
+	TrackKeyEnv     string `json:"track_key_env,omitempty"`
+	TrackKeyCommand string `json:"track_key_command,omitempty"`
+
+	WriteKeyEnv     string `json:"write_key_env,omitempty"`
+	WriteKeyCommand string `json:"write_key_command,omitempty"`
+
+	// ReadOnly refuses requests that could change data.
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	// AuditLog is where requests that change data are recorded: a file
+	// path, "syslog" or "off". AuditBody is "hash" or "redacted".
+	AuditLog  string `json:"audit_log,omitempty"`
+	AuditBody string `json:"audit_body,omitempty"`
+
+	// Cache keeps GET responses for some resources on disk; CacheTTLs
+	// overrides how long, per resource.
+	Cache     bool              `json:"cache,omitempty"`
+	CacheTTLs map[string]string `json:"cache_ttls,omitempty"`
+}
+
+type Config struct {
+	CurrentProfile string              `json:"current_profile,omitempty"`
+	Profiles       map[string]*Profile `json:"profiles,omitempty"`
+
+	path string
+}
+
+// Path returns the config file location: $CIO_CONFIG if set, otherwise
+// $XDG_CONFIG_HOME/cio/config.json, falling back to ~/.config.
+func Path() (string, error) {
+	if p := os.Getenv("CIO_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "cio", "config.json"), nil
+}
+
+// Load reads the config file. A missing file yields an empty config.
+func Load() (*Config, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for name, p := range cfg.Profiles {
+		if p == nil {
+			cfg.Profiles[name] = &Profile{}
+		}
+		cfg.Profiles[name].Name = name
+	}
+	return cfg, nil
+}
+
+func (c *Config) Save() error {
+	if c.path == "" {
+		path, err := Path()
+		if err != nil {
+			return err
+		}
+		c.path = path
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0o600)
+}
+
+func (c *Config) File() string {
+	return c.path
+}
+
+// ActiveName picks the profile name: the explicit name (from --profile),
+// then $CIO_PROFILE, then the config's current profile, then "default".
+// The second result says which of those it came from.
+func (c *Config) ActiveName(explicit string) (name, source string) {
+	switch {
+	case explicit != "":
+		return explicit, "flag"
+	case os.Getenv("CIO_PROFILE") != "":
+		return os.Getenv("CIO_PROFILE"), "env"
+	case c.CurrentProfile != "":
+		return c.CurrentProfile, "config"
+	}
+	return DefaultProfile, "default"
+}
+
+// Active returns a copy of the active profile. Only the implicit default
+// profile may be absent from the file; naming a missing profile is an
+// error.
+func (c *Config) Active(explicit string) (*Profile, error) {
+	name, _ := c.ActiveName(explicit)
+	p, ok := c.Profiles[name]
+	if !ok {
+		if name != DefaultProfile {
+			return nil, fmt.Errorf("profile %q not found (create it with: cio config set --profile %s region us)", name, name)
+		}
+		return &Profile{Name: name}, nil
+	}
+	cp := *p
+	cp.RateLimits = make(map[string]string, len(p.RateLimits))
+	for k, v := range p.RateLimits {
+		cp.RateLimits[k] = v
+	}
+	cp.CacheTTLs = make(map[string]string, len(p.CacheTTLs))
+	for k, v := range p.CacheTTLs {
+		cp.CacheTTLs[k] = v
+	}
+	return &cp, nil
+}
+
+// Profile returns the named profile, creating it if needed.
+func (c *Config) Profile(name string) *Profile {
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*Profile)
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		p = &Profile{Name: name}
+		c.Profiles[name] = p
+	}
+	return p
+}
+
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *Profile) TokenEnvName() string {
+	if p.TokenEnv != "" {
+		return p.TokenEnv
+	}
+	return DefaultTokenEnv
+}
+
+func (p *Profile) TrackKeyEnvName() string {
+	if p.TrackKeyEnv != "" {
+		return p.TrackKeyEnv
+	}
+	return DefaultTrackKeyEnv
+}
+
+func (p *Profile) WriteKeyEnvName() string {
+	if p.WriteKeyEnv != "" {
+		return p.WriteKeyEnv
+	}
+	return DefaultWriteKeyEnv
+}
+
+// Keys lists the settable profile keys, for help and error messages.
+var Keys = []string{"region", "base_url", "token_env", "token_file", "token_command", "rate_limits.<class>",
+	"site_id", "track_key_env", "track_key_command", "write_key_env", "write_key_command", "read_only",
+	"audit_log", "audit_body", "cache", "cache_ttls.<resource>"}
+
+func (p *Profile) Get(key string) (string, error) {
+	switch key {
+	case "region":
+		return p.Region, nil
+	case "base_url":
+		return p.BaseURL, nil
+	case "token_env":
+		return p.TokenEnv, nil
+	case "token_file":
+		return p.TokenFile, nil
+	case "token_command":
+		return p.TokenCommand, nil
+	case "site_id":
+		return p.SiteID, nil
+	case "track_key_env":
+		return p.TrackKeyEnv, nil
+	case "track_key_command":
+		return p.TrackKeyCommand, nil
+	case "write_key_env":
+		return p.WriteKeyEnv, nil
+	case "write_key_command":
+		return p.WriteKeyCommand, nil
+	case "audit_log":
+		return p.AuditLog, nil
+	case "audit_body":
+		return p.AuditBody, nil
+	case "read_only":
+		if p.ReadOnly {
+			return "true", nil
+		}
+		return "", nil
+	case "cache":
+		if p.Cache {
+			return "true", nil
+		}
+		return "", nil
+	}
+	if class, ok := strings.CutPrefix(key, "rate_limits."); ok {
+		return p.RateLimits[class], nil
+	}
+	if resource, ok := strings.CutPrefix(key, "cache_ttls."); ok {
+		return p.CacheTTLs[resource], nil
+	}
+	return "", unknownKey(key)
+}
+
+// Set updates a key; an empty value clears it.
+func (p *Profile) Set(key, value string) error {
+	switch key {
+	case "region":
+		if value != "" && value != "us" && value != "eu" {
+			return fmt.Errorf("invalid region %q (want us or eu)", value)
+		}
+		p.Region = value
+	case "base_url":
+		p.BaseURL = strings.TrimRight(value, "/")
+	case "token_env":
+		p.TokenEnv = value
+	case "token_file":
+		p.TokenFile = value
+	case "token_command":
+		p.TokenCommand = value
+	case "site_id":
+		p.SiteID = value
+	case "track_key_env":
+		p.TrackKeyEnv = value
+	case "track_key_command":
+		p.TrackKeyCommand = value
+	case "write_key_env":
+		p.WriteKeyEnv = value
+	case "write_key_command":
+		p.WriteKeyCommand = value
+	case "read_only":
+		if value == "" {
+			p.ReadOnly = false
+			break
+		}
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid read_only %q (want true or false)", value)
+		}
+		p.ReadOnly = v
+	case "cache":
+		if value == "" {
+			p.Cache = false
+			break
+		}
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid cache %q (want true or false)", value)
+		}
+		p.Cache = v
+	case "audit_log":
+		p.AuditLog = value
+	case "audit_body":
+		if value != "" && value != "hash" && value != "redacted" {
+			return fmt.Errorf("invalid audit_body %q (want hash or redacted)", value)
+		}
+		p.AuditBody = value
+	default:
+		if resource, ok := strings.CutPrefix(key, "cache_ttls."); ok && resource != "" {
+			if value == "" {
+				delete(p.CacheTTLs, resource)
+				return nil
+			}
+			if d, err := time.ParseDuration(value); err != nil || d < 0 {
+				return fmt.Errorf("invalid cache TTL %q (want a duration like 10m, or 0 to disable)", value)
+			}
+			if p.CacheTTLs == nil {
+				p.CacheTTLs = make(map[string]string)
+			}
+			p.CacheTTLs[resource] = value
+			return nil
+		}
+		class, ok := strings.CutPrefix(key, "rate_limits.")
+		if !ok || class == "" {
+			return unknownKey(key)
+		}
+		if value == "" {
+			delete(p.RateLimits, class)
+			return nil
+		}
+		if p.RateLimits == nil {
+			p.RateLimits = make(map[string]string)
+		}
+		p.RateLimits[class] = value
+	}
+	return nil
+}
+
+func unknownKey(key string) error {
+	return fmt.Errorf("unknown config key %q (want one of: %s)", key, strings.Join(Keys, ", "))
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempConfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cio", "config.json")
+	t.Setenv("CIO_CONFIG", path)
+	t.Setenv("CIO_PROFILE", "")
+	return path
+}
+
+func TestPath(t *testing.T) {
+	t.Run("explicit", func(t *testing.T) {
+		t.Setenv("CIO_CONFIG", "/tmp/cio.json")
+		p, err := Path()
+		if err != nil || p != "/tmp/cio.json" {
+			t.Fatalf("got %s, %v", p, err)
+		}
+	})
+
+	t.Run("xdg", func(t *testing.T) {
+		t.Setenv("CIO_CONFIG", "")
+		t.Setenv("XDG_CONFIG_HOME", "/xdg")
+		p, err := Path()
+		if err != nil || p != filepath.Join("/xdg", "cio", "config.json") {
+			t.Fatalf("got %s, %v", p, err)
+		}
+	})
+
+	t.Run("home fallback", func(t *testing.T) {
+		t.Setenv("CIO_CONFIG", "")
+		t.Setenv("XDG_CONFIG_HOME", "")
+		t.Setenv("HOME", "/home/u")
+		p, err := Path()
+		if err != nil || p != filepath.Join("/home/u", ".config", "cio", "config.json") {
+			t.Fatalf("got %s, %v", p, err)
+		}
+	})
+}
+
+func TestLoadMissing(t *testing.T) {
+	tempConfig(t)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := cfg.Active("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != DefaultProfile || p.TokenEnvName() != DefaultTokenEnv {
+		t.Fatalf("got %+v", p)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := tempConfig(t)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	staging := cfg.Profile("staging")
+	if err := staging.Set("region", "eu"); err != nil {
+		t.Fatal(err)
+	}
+	if err := staging.Set("token_env", "CIO_STAGING_TOKEN"); err != nil {
+		t.Fatal(err)
+	}
+	if err := staging.Set("rate_limits.general", "5/s"); err != nil {
+		t.Fatal(err)
+	}
+	cfg.CurrentProfile = "staging"
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode = %v", info.Mode().Perm())
+	}
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := cfg.Active("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "staging" || p.Region != "eu" || p.TokenEnvName() != "CIO_STAGING_TOKEN" || p.RateLimits["general"] != "5/s" {
+		t.Fatalf("got %+v", p)
+	}
+}
+
+func TestActiveName(t *testing.T) {
+	tempConfig(t)
+	cfg := &Config{CurrentProfile: "prod"}
+
+	if name, src := cfg.ActiveName("flag"); name != "flag" || src != "flag" {
+		t.Fatalf("got %s, %s", name, src)
+	}
+	t.Setenv("CIO_PROFILE", "envp")
+	if name, src := cfg.ActiveName(""); name != "envp" || src != "env" {
+		t.Fatalf("got %s, %s", name, src)
+	}
+	t.Setenv("CIO_PROFILE", "")
+	if name, src := cfg.ActiveName(""); name != "prod" || src != "config" {
+		t.Fatalf("got %s, %s", name, src)
+	}
+	if name, src := (&Config{}).ActiveName(""); name != DefaultProfile || src != "default" {
+		t.Fatalf("got %s, %s", name, src)
+	}
+}
+
+func TestActiveMissingProfile(t *testing.T) {
+	tempConfig(t)
+	if _, err := (&Config{}).Active("nope"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestActiveReturnsCopy(t *testing.T) {
+	cfg := &Config{Profiles: map[string]*Profile{"default": {Name: "default", RateLimits: map[string]string{"general": "1/s"}}}}
+	p, err := cfg.Active("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Region = "eu"
+	p.RateLimits["general"] = "9/s"
+	if cfg.Profiles["default"].Region != "" || cfg.Profiles["default"].RateLimits["general"] != "1/s" {
+		t.Fatal("Active leaked a reference to the stored profile")
+	}
+}
+
+func TestProfileSetGet(t *testing.T) {
+	p := &Profile{}
+	if err := p.Set("region", "asia"); err == nil {
+		t.Fatal("expected invalid region error")
+	}
+	if err := p.Set("bogus", "x"); err == nil {
+		t.Fatal("expected unknown key error")
+	}
+	if err := p.Set("base_url", "https://proxy.example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := p.Get("base_url"); v != "https://proxy.example.com" {
+		t.Fatalf("got %s", v)
+	}
+	if err := p.Set("rate_limits.broadcast", "1/30s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Set("rate_limits.broadcast", ""); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := p.Get("rate_limits.broadcast"); v != "" {
+		t.Fatalf("got %s", v)
+	}
+	if p.TrackKeyEnvName() != DefaultTrackKeyEnv {
+		t.Fatalf("got %s", p.TrackKeyEnvName())
+	}
+	if err := p.Set("track_key_env", "CIO_STAGING_TRACK_KEY"); err != nil {
+		t.Fatal(err)
+	}
+	if p.TrackKeyEnvName() != "CIO_STAGING_TRACK_KEY" {
+		t.Fatalf("got %s", p.TrackKeyEnvName())
+	}
+	if err := p.Set("read_only", "maybe"); err == nil {
+		t.Fatal("expected invalid read_only error")
+	}
+	if err := p.Set("read_only", "true"); err != nil || !p.ReadOnly {
+		t.Fatalf("read_only not set: %v", err)
+	}
+	if v, _ := p.Get("read_only"); v != "true" {
+		t.Fatalf("got %s", v)
+	}
+	if err := p.Set("read_only", ""); err != nil || p.ReadOnly {
+		t.Fatalf("read_only not cleared: %v", err)
+	}
+	if err := p.Set("audit_body", "full"); err == nil {
+		t.Fatal("expected invalid audit_body error")
+	}
+	if err := p.Set("cache", "true"); err != nil || !p.Cache {
+		t.Fatalf("cache not set: %v", err)
+	}
+	if err := p.Set("cache_ttls.segments", "soon"); err == nil {
+		t.Fatal("expected invalid cache TTL error")
+	}
+	if err := p.Set("cache_ttls.segments", "10m"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := p.Get("cache_ttls.segments"); v != "10m" {
+		t.Fatalf("got %s", v)
+	}
+	if err := p.Set("cache_ttls.segments", ""); err != nil || len(p.CacheTTLs) != 0 {
+		t.Fatalf("cache TTL not cleared: %v", err)
+	}
+}
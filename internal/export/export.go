@@ -0,0 +1,182 @@
+// Package export decodes Customer.io export files: gzip is undone on the
+// fly and rows can be converted between CSV and newline-delimited JSON
+// without holding the file in memory.
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Output formats.
+const (
+	CSV    = "csv"
+	NDJSON = "ndjson"
+)
+
+// Convert copies an export from src to dst, decompressing it if it is
+// gzipped. With a format, rows are converted to it; the source format is
+// detected from the first byte, '{' meaning NDJSON and anything else CSV.
+// An empty format keeps the file as it is.
+func Convert(dst io.Writer, src io.Reader, format string) error {
+	switch format {
+	case "", CSV, NDJSON:
+	default:
+		return fmt.Errorf("invalid format %q (want csv or ndjson)", format)
+	}
+	br := bufio.NewReaderSize(src, 64<<10)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		br = bufio.NewReaderSize(zr, 64<<10)
+	}
+
+	bw := bufio.NewWriterSize(dst, 64<<10)
+	var err error
+	switch source := detect(br); {
+	case format == "" || format == source:
+		_, err = io.Copy(bw, br)
+	case format == NDJSON:
+		err = csvToNDJSON(bw, br)
+	default:
+		err = ndjsonToCSV(bw, br)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func detect(br *bufio.Reader) string {
+	for n := 1; ; n++ {
+		b, err := br.Peek(n)
+		if err != nil || len(b) < n {
+			return CSV
+		}
+		switch b[n-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{':
+			return NDJSON
+		}
+		return CSV
+	}
+}
+
+// csvToNDJSON writes one object per row, keyed by the header row, with
+// fields in column order and every value a string.
+func csvToNDJSON(w io.Writer, r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	keys := make([][]byte, len(header))
+	for i, h := range header {
+		if keys[i], err = json.Marshal(h); err != nil {
+			return err
+		}
+	}
+
+	var line bytes.Buffer
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line.Reset()
+		line.WriteByte('{')
+		for i, v := range record {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.Write(keys[i])
+			line.WriteByte(':')
+			val, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			line.Write(val)
+		}
+		line.WriteString("}\n")
+		if _, err := w.Write(line.Bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+// ndjsonToCSV takes its columns from the first object's keys, sorted.
+// Strings are written as is, null as an empty field and anything else as
+// compact JSON. A later object with a key the first one lacked is an error
+// rather than a silently dropped field.
+func ndjsonToCSV(w io.Writer, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	cw := csv.NewWriter(w)
+	var columns []string
+	var known map[string]bool
+	for n := 1; ; n++ {
+		var obj map[string]json.RawMessage
+		if err := dec.Decode(&obj); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+		if columns == nil {
+			known = make(map[string]bool, len(obj))
+			for k := range obj {
+				columns = append(columns, k)
+				known[k] = true
+			}
+			sort.Strings(columns)
+			if err := cw.Write(columns); err != nil {
+				return err
+			}
+		}
+		for k := range obj {
+			if !known[k] {
+				return fmt.Errorf("record %d: field %q is not in the first record", n, k)
+			}
+		}
+		row := make([]string, len(columns))
+		for i, k := range columns {
+			row[i] = csvValue(obj[k])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(v json.RawMessage) string {
+	if len(v) == 0 || string(v) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, v) == nil {
+		return buf.String()
+	}
+	return string(v)
+}
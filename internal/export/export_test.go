@@ -0,0 +1,79 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+const sampleCSV = "id,email,name\n1,a@example.com,\"Doe, Jane\"\n2,b@example.com,Bob\n"
+
+func gzipped(t *testing.T, s string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestConvertGunzips(t *testing.T) {
+	var out bytes.Buffer
+	if err := Convert(&out, gzipped(t, sampleCSV), ""); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != sampleCSV {
+		t.Fatalf("got %q", out.String())
+	}
+}
+
+func TestConvertPlainPassthrough(t *testing.T) {
+	var out bytes.Buffer
+	if err := Convert(&out, strings.NewReader(sampleCSV), CSV); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != sampleCSV {
+		t.Fatalf("got %q", out.String())
+	}
+}
+
+func TestConvertCSVToNDJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := Convert(&out, gzipped(t, sampleCSV), NDJSON); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"1","email":"a@example.com","name":"Doe, Jane"}` + "\n" +
+		`{"id":"2","email":"b@example.com","name":"Bob"}` + "\n"
+	if out.String() != want {
+		t.Fatalf("got %q", out.String())
+	}
+}
+
+func TestConvertNDJSONToCSV(t *testing.T) {
+	in := `{"id":1,"email":"a@example.com","tags":["x"],"name":null}` + "\n" +
+		`{"id":2,"email":"b@example.com"}` + "\n"
+	var out bytes.Buffer
+	if err := Convert(&out, strings.NewReader(in), CSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "email,id,name,tags\na@example.com,1,,\"[\"\"x\"\"]\"\nb@example.com,2,,\n"
+	if out.String() != want {
+		t.Fatalf("got %q", out.String())
+	}
+
+	in += `{"id":3,"extra":true}` + "\n"
+	if err := Convert(&out, strings.NewReader(in), CSV); err == nil || !strings.Contains(err.Error(), `record 3: field "extra"`) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestConvertInvalidFormat(t *testing.T) {
+	if err := Convert(&bytes.Buffer{}, strings.NewReader(sampleCSV), "xml"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,301 @@
+// Package filter compiles a small expression language into Customer.io's
+// audience filter JSON, the and/or/not tree of segment and attribute
+// conditions taken by customers search, objects search and customer
+// exports.
+//
+//	segment=3 and email exists and not unsubscribed=true
+//	(plan = "pro" or plan = enterprise) and created_at >= 1700000000
+//
+// Conditions are segment=<id>, segment!=<id>, <field> exists,
+// <field> not exists (or not_exists), and <field> <op> <value> with =
+// (eq), != (ne), >, >=, <, <= or a named operator: eq, ne, gt, gte, lt,
+// lte, starts_with, ends_with, contains. not binds tighter than and, which
+// binds tighter than or. Field names and values may be quoted with " or '; quote a field
+// called "segment" to use it as an attribute. Attribute values are always
+// sent as strings, as the API expects.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operators maps every accepted spelling to the API operator. exists and
+// not_exists take no value.
+var Operators = map[string]string{
+	"=": "eq", "==": "eq", "!=": "ne",
+	">": "gt", ">=": "gte", "<": "lt", "<=": "lte",
+	"eq": "eq", "ne": "ne", "gt": "gt", "gte": "gte", "lt": "lt", "lte": "lte",
+	"starts_with": "starts_with", "ends_with": "ends_with", "contains": "contains",
+	"exists": "exists", "not_exists": "not_exists",
+}
+
+// Node is one node of the filter tree, ready to marshal.
+type Node map[string]any
+
+// Compile parses expr into a filter. The top level is always an and or an
+// or, which the API requires.
+func Compile(expr string) (Node, error) {
+	toks, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	if _, ok := n["and"]; !ok {
+		if _, ok := n["or"]; !ok {
+			n = Node{"and": []Node{n}}
+		}
+	}
+	return n, nil
+}
+
+// CompileJSON is Compile returning the filter as JSON.
+func CompileJSON(expr string) (json.RawMessage, error) {
+	n, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(n)
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// keyword reports whether t is the unquoted word w, ignoring case.
+func (t token) keyword(w string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, w)
+}
+
+func lex(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == '"' || c == '\'':
+			start := i
+			var b strings.Builder
+			for i++; ; i++ {
+				if i >= len(s) {
+					return nil, fmt.Errorf("column %d: unterminated string", start+1)
+				}
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					b.WriteByte(s[i])
+					continue
+				}
+				if s[i] == c {
+					break
+				}
+				b.WriteByte(s[i])
+			}
+			i++
+			toks = append(toks, token{tokString, b.String(), start})
+		case strings.ContainsRune("=!<>", rune(c)):
+			start := i
+			i++
+			if i < len(s) && s[i] == '=' {
+				i++
+			}
+			op := s[start:i]
+			if op == "!" {
+				return nil, fmt.Errorf("column %d: unexpected \"!\" (use != or not)", start+1)
+			}
+			toks = append(toks, token{tokOp, op, start})
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n\r()\"'=!<>", rune(s[i])) {
+				i++
+			}
+			toks = append(toks, token{tokWord, s[start:i], start})
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(s)}), nil
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("column %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) or() (Node, error) {
+	return p.list("or", p.and)
+}
+
+func (p *parser) and() (Node, error) {
+	return p.list("and", p.unary)
+}
+
+// list parses operands joined by the keyword op into one flat list.
+func (p *parser) list(op string, operand func() (Node, error)) (Node, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{first}
+	for p.peek().keyword(op) {
+		p.next()
+		n, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	if len(nodes) == 1 {
+		return first, nil
+	}
+	return Node{op: nodes}, nil
+}
+
+func (p *parser) unary() (Node, error) {
+	if p.peek().keyword("not") {
+		p.next()
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Node{"not": n}, nil
+	}
+	if p.peek().kind == tokLParen {
+		open := p.next()
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, p.errorf(t, "expected \")\" to close the \"(\" at column %d, got %s", open.pos+1, t)
+		}
+		return n, nil
+	}
+	return p.condition()
+}
+
+func (p *parser) condition() (Node, error) {
+	field := p.next()
+	switch {
+	case field.kind != tokWord && field.kind != tokString:
+		return nil, p.errorf(field, "expected a condition, got %s", field)
+	case field.kind == tokWord && isKeyword(field.text):
+		return nil, p.errorf(field, "expected a condition, got %s", field)
+	case field.keyword("segment"):
+		return p.segment(field)
+	}
+
+	t := p.next()
+	if t.keyword("not") {
+		if e := p.next(); !e.keyword("exists") {
+			return nil, p.errorf(e, "expected \"exists\" after \"not\", got %s", e)
+		}
+		return Node{"not": attribute(field.text, "exists", nil)}, nil
+	}
+	op, ok := operator(t)
+	if !ok {
+		return nil, p.errorf(t, "expected an operator after %s, got %s", field, t)
+	}
+	switch op {
+	case "exists":
+		return attribute(field.text, "exists", nil), nil
+	case "not_exists":
+		return Node{"not": attribute(field.text, "exists", nil)}, nil
+	}
+	v := p.next()
+	if v.kind != tokWord && v.kind != tokString {
+		return nil, p.errorf(v, "expected a value after %s, got %s", t, v)
+	}
+	return attribute(field.text, op, v.text), nil
+}
+
+func (p *parser) segment(field token) (Node, error) {
+	t := p.next()
+	if t.kind != tokOp || (t.text != "=" && t.text != "==" && t.text != "!=") {
+		return nil, p.errorf(t, "expected = or != after segment, got %s", t)
+	}
+	v := p.next()
+	id, err := strconv.Atoi(v.text)
+	if (v.kind != tokWord && v.kind != tokString) || err != nil || id < 0 {
+		return nil, p.errorf(v, "segment id must be a number, got %s", v)
+	}
+	n := Node{"segment": Node{"id": id}}
+	if t.text == "!=" {
+		n = Node{"not": n}
+	}
+	return n, nil
+}
+
+func operator(t token) (string, bool) {
+	if t.kind != tokOp && t.kind != tokWord {
+		return "", false
+	}
+	op, ok := Operators[strings.ToLower(t.text)]
+	return op, ok
+}
+
+func isKeyword(w string) bool {
+	switch strings.ToLower(w) {
+	case "and", "or", "not", "exists":
+		return true
+	}
+	return false
+}
+
+func attribute(field, op string, value any) Node {
+	a := Node{"field": field, "operator": op}
+	if value != nil {
+		a["value"] = value
+	}
+	return Node{"attribute": a}
+}
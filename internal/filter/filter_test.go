@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{
+			`segment=3 and email exists and not unsubscribed=true`,
+			`{"and":[{"segment":{"id":3}},{"attribute":{"field":"email","operator":"exists"}},{"not":{"attribute":{"field":"unsubscribed","operator":"eq","value":"true"}}}]}`,
+		},
+		{
+			`segment=3`,
+			`{"and":[{"segment":{"id":3}}]}`,
+		},
+		{
+			`segment != 4 or plan ne free`,
+			`{"or":[{"not":{"segment":{"id":4}}},{"attribute":{"field":"plan","operator":"ne","value":"free"}}]}`,
+		},
+		{
+			`(plan = "pro plan" or plan = enterprise) and created_at >= 1700000000`,
+			`{"and":[{"or":[{"attribute":{"field":"plan","operator":"eq","value":"pro plan"}},{"attribute":{"field":"plan","operator":"eq","value":"enterprise"}}]},{"attribute":{"field":"created_at","operator":"gte","value":"1700000000"}}]}`,
+		},
+		{
+			`email starts_with 'ops@' and phone not exists`,
+			`{"and":[{"attribute":{"field":"email","operator":"starts_with","value":"ops@"}},{"not":{"attribute":{"field":"phone","operator":"exists"}}}]}`,
+		},
+		{
+			`phone not_exists or PHONE NOT EXISTS`,
+			`{"or":[{"not":{"attribute":{"field":"phone","operator":"exists"}}},{"not":{"attribute":{"field":"PHONE","operator":"exists"}}}]}`,
+		},
+		{
+			`a=1 or b=2 and c=3`,
+			`{"or":[{"attribute":{"field":"a","operator":"eq","value":"1"}},{"and":[{"attribute":{"field":"b","operator":"eq","value":"2"}},{"attribute":{"field":"c","operator":"eq","value":"3"}}]}]}`,
+		},
+		{
+			`"segment" = vip AND NOT score < 10`,
+			`{"and":[{"attribute":{"field":"segment","operator":"eq","value":"vip"}},{"not":{"attribute":{"field":"score","operator":"lt","value":"10"}}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := CompileJSON(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{``, `column 1: expected a condition, got end of expression`},
+		{`segment=abc`, `column 9: segment id must be a number, got "abc"`},
+		{`segment > 3`, `column 9: expected = or != after segment`},
+		{`email like foo`, `column 7: expected an operator after "email", got "like"`},
+		{`plan =`, `column 7: expected a value after "=", got end of expression`},
+		{`(a=1 or b=2`, `column 12: expected ")" to close the "(" at column 1`},
+		{`a=1 b=2`, `column 5: unexpected "b"`},
+		{`email not empty`, `column 11: expected "exists" after "not"`},
+		{`name = "open`, `column 8: unterminated string`},
+		{`a ! b`, `column 3: unexpected "!"`},
+		{`and a=1`, `column 1: expected a condition, got "and"`},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.expr)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want prefix %q", tt.expr, err, tt.want)
+		}
+	}
+}
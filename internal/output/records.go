@@ -0,0 +1,233 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Record formats.
+const (
+	CSV    = "csv"
+	TSV    = "tsv"
+	NDJSON = "ndjson"
+)
+
+// RecordWriter writes the rows of list responses as CSV, TSV or NDJSON,
+// one response (or page) per Write, so paginated output streams. Nested
+// objects are flattened into dotted keys such as metrics.sent, in every
+// format. Without Columns the columns come from the first rows written and
+// stay fixed, so every page lines up under one header and every NDJSON line
+// has the same keys; fields that first appear later are left out, with a
+// warning. SortBy sorts the rows of each Write, so sorting a whole listing
+// takes a single Write.
+type RecordWriter struct {
+	// Warnings, when set, is told once about fields left out because they
+	// were not in the first rows.
+	Warnings io.Writer
+
+	w       io.Writer
+	format  string
+	opts    TableOptions
+	columns []string
+	header  bool
+	warned  bool
+}
+
+// NewRecordWriter returns a writer for format. Width in opts is ignored,
+// and NoHeaders only matters for CSV and TSV.
+func NewRecordWriter(w io.Writer, format string, opts TableOptions) (*RecordWriter, error) {
+	switch format {
+	case CSV, TSV, NDJSON:
+	default:
+		return nil, fmt.Errorf("unknown record format %q (want csv, tsv or ndjson)", format)
+	}
+	return &RecordWriter{w: w, format: format, opts: opts, columns: opts.Columns}, nil
+}
+
+// Write writes the rows of one response. A response without a list is
+// written as a single row.
+func (r *RecordWriter) Write(data json.RawMessage) error {
+	v, err := decodeValue(data)
+	if err != nil {
+		return fmt.Errorf("cannot write %s: the response is not JSON", r.format)
+	}
+	_, rows, ok := Rows(v)
+	if !ok {
+		obj, isObj := record(v).(map[string]any)
+		if !isObj {
+			obj = map[string]any{"value": v}
+		}
+		rows = []map[string]any{obj}
+	}
+	if r.opts.SortBy != "" {
+		sortRows(rows, r.opts.SortBy)
+	}
+
+	flat := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		flat[i] = Flatten(row)
+	}
+	if r.columns == nil {
+		if len(flat) == 0 {
+			return nil
+		}
+		r.columns = recordColumns(flat)
+	} else if len(r.opts.Columns) == 0 {
+		r.warnDropped(flat)
+	}
+
+	var buf bytes.Buffer
+	if r.format == NDJSON {
+		err = r.writeNDJSON(&buf, flat)
+	} else {
+		err = r.writeDelimited(&buf, flat)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(r.w)
+	return err
+}
+
+// warnDropped reports fields of rows that have no column.
+func (r *RecordWriter) warnDropped(rows []map[string]any) {
+	if r.warned || r.Warnings == nil {
+		return
+	}
+	have := make(map[string]bool, len(r.columns))
+	for _, c := range r.columns {
+		have[c] = true
+	}
+	missing := map[string]bool{}
+	for _, row := range rows {
+		for k := range row {
+			if !have[k] {
+				missing[k] = true
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	names := make([]string, 0, len(missing))
+	for k := range missing {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	r.warned = true
+	fmt.Fprintf(r.Warnings, "warning: leaving out fields not in the first page: %s (choose them with --columns)\n", strings.Join(names, ", "))
+}
+
+// writeNDJSON writes each row as an object with the columns as keys, in
+// column order.
+func (r *RecordWriter) writeNDJSON(buf *bytes.Buffer, rows []map[string]any) error {
+	var v bytes.Buffer
+	enc := json.NewEncoder(&v)
+	enc.SetEscapeHTML(false)
+	encode := func(x any) error {
+		v.Reset()
+		if err := enc.Encode(x); err != nil {
+			return err
+		}
+		buf.Write(bytes.TrimSpace(v.Bytes()))
+		return nil
+	}
+	for _, row := range rows {
+		buf.WriteByte('{')
+		for i, c := range r.columns {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encode(c); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encode(row[c]); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}\n")
+	}
+	return nil
+}
+
+func (r *RecordWriter) writeDelimited(buf *bytes.Buffer, rows []map[string]any) error {
+	cw := csv.NewWriter(buf)
+	write := func(fields []string) error {
+		if r.format == CSV {
+			return cw.Write(fields)
+		}
+		// TSV has no quoting, so tabs and line breaks become spaces.
+		for i, f := range fields {
+			fields[i] = cellReplacer.Replace(f)
+		}
+		buf.WriteString(strings.Join(fields, "\t"))
+		buf.WriteByte('\n')
+		return nil
+	}
+	if !r.header && !r.opts.NoHeaders {
+		if err := write(append([]string(nil), r.columns...)); err != nil {
+			return err
+		}
+	}
+	r.header = true
+	line := make([]string, len(r.columns))
+	for _, row := range rows {
+		for i, c := range r.columns {
+			line[i] = text(row[c])
+		}
+		if err := write(line); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Flatten turns nested objects into dotted keys: {"a":{"b":1}} becomes
+// {"a.b":1}. Arrays are kept as values.
+func Flatten(obj map[string]any) map[string]any {
+	out := make(map[string]any, len(obj))
+	flattenInto(out, "", obj)
+	return out
+}
+
+func flattenInto(out map[string]any, prefix string, obj map[string]any) {
+	for k, v := range obj {
+		if inner, ok := v.(map[string]any); ok && len(inner) > 0 {
+			flattenInto(out, prefix+k+".", inner)
+			continue
+		}
+		out[prefix+k] = v
+	}
+}
+
+// recordColumns is every key of rows, id and name first and the rest in
+// order.
+func recordColumns(rows []map[string]any) []string {
+	seen := map[string]bool{}
+	var rest []string
+	for _, row := range rows {
+		for k := range row {
+			if !seen[k] {
+				seen[k] = true
+				if k != "id" && k != "name" {
+					rest = append(rest, k)
+				}
+			}
+		}
+	}
+	sort.Strings(rest)
+	var cols []string
+	for _, k := range []string{"id", "name"} {
+		if seen[k] {
+			cols = append(cols, k)
+		}
+	}
+	return append(cols, rest...)
+}
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func writeRecords(t *testing.T, format string, opts TableOptions, pages ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	rw, err := NewRecordWriter(&buf, format, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pages {
+		if err := rw.Write(json.RawMessage(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.String()
+}
+
+const (
+	messagesPage1 = `{"messages":[{"id":"m1","type":"email","metrics":{"sent":1,"opened":2},"subject":"Hi, there"}],"next":"c1"}`
+	messagesPage2 = `{"messages":[{"id":"m2","type":"push","metrics":{"sent":3},"extra":true}],"next":""}`
+)
+
+func TestRecordsCSVStableColumns(t *testing.T) {
+	got := writeRecords(t, CSV, TableOptions{}, messagesPage1, `{"messages":[]}`, messagesPage2)
+	want := "" +
+		"id,metrics.opened,metrics.sent,subject,type\n" +
+		"m1,2,1,\"Hi, there\",email\n" +
+		"m2,,3,,push\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRecordsWarnDroppedFields(t *testing.T) {
+	var out, warnings bytes.Buffer
+	rw, err := NewRecordWriter(&out, CSV, TableOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw.Warnings = &warnings
+	for _, p := range []string{messagesPage1, messagesPage2, messagesPage2} {
+		if err := rw.Write(json.RawMessage(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "warning: leaving out fields not in the first page: extra (choose them with --columns)\n"
+	if warnings.String() != want {
+		t.Fatalf("got %q", warnings.String())
+	}
+}
+
+func TestRecordsTSV(t *testing.T) {
+	got := writeRecords(t, TSV, TableOptions{Columns: []string{"id", "note"}, NoHeaders: true},
+		`[{"id":1,"note":"tab\there\nand a line"},{"id":2,"note":["a"]}]`)
+	want := "1\ttab here and a line\n2\t[\"a\"]\n"
+	if got != want {
+		t.Fatalf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestRecordsNDJSON(t *testing.T) {
+	got := writeRecords(t, NDJSON, TableOptions{}, messagesPage1, messagesPage2)
+	want := "" +
+		`{"id":"m1","metrics.opened":2,"metrics.sent":1,"subject":"Hi, there","type":"email"}` + "\n" +
+		`{"id":"m2","metrics.opened":null,"metrics.sent":3,"subject":null,"type":"push"}` + "\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = writeRecords(t, NDJSON, TableOptions{Columns: []string{"metrics.sent", "id"}, SortBy: "-metrics.sent"},
+		`{"messages":[{"id":"m1","metrics":{"sent":1}},{"id":"m2","metrics":{"sent":3}}]}`)
+	want = `{"metrics.sent":3,"id":"m2"}` + "\n" + `{"metrics.sent":1,"id":"m1"}` + "\n"
+	if got != want {
+		t.Fatalf("columns:\n%s", got)
+	}
+}
+
+func TestRecordsSingleObjectAndScalars(t *testing.T) {
+	got := writeRecords(t, CSV, TableOptions{}, `{"customer":{"id":"u1","attributes":{"plan":"pro"}}}`)
+	if got != "id,attributes.plan\nu1,pro\n" {
+		t.Fatalf("got:\n%s", got)
+	}
+	got = writeRecords(t, CSV, TableOptions{}, `{"ids":["a","b"]}`)
+	if got != "value\na\nb\n" {
+		t.Fatalf("got:\n%s", got)
+	}
+}
+
+func TestNewRecordWriterRejectsFormat(t *testing.T) {
+	if _, err := NewRecordWriter(&bytes.Buffer{}, "xml", TableOptions{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
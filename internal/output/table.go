@@ -0,0 +1,359 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultColumns are the columns shown for a list, keyed by the field that
+// holds its items. Lists not named here show the id and name, when
+// present, followed by the first item's other scalar fields.
+var DefaultColumns = map[string][]string{
+	"segments":           {"id", "name", "type", "state"},
+	"campaigns":          {"id", "name", "type", "state", "active"},
+	"broadcasts":         {"id", "name", "state", "active"},
+	"newsletters":        {"id", "name", "type", "sent_at"},
+	"messages":           {"id", "type", "recipient", "subject", "created"},
+	"deliveries":         {"id", "type", "recipient", "subject", "created"},
+	"activities":         {"id", "type", "name", "customer_id", "timestamp"},
+	"identifiers":        {"id", "email", "cio_id"},
+	"exports":            {"id", "type", "status", "total", "created_at"},
+	"imports":            {"id", "name", "type", "status", "created_at"},
+	"collections":        {"id", "name", "rows", "updated_at"},
+	"reporting_webhooks": {"id", "name", "endpoint", "disabled"},
+	"snippets":           {"name", "updated_at"},
+	"sender_identities":  {"id", "name", "email", "type"},
+	"topics":             {"id", "name", "description"},
+	"workspaces":         {"id", "name", "messages_sent"},
+}
+
+// maxDefaultColumns caps how many columns a list without defaults gets.
+const maxDefaultColumns = 8
+
+// TableOptions controls PrintTable. Columns may be dotted paths into nested
+// objects, e.g. metrics.sent. SortBy names a column, descending with a
+// leading "-". Width, when positive, truncates cells so rows fit.
+type TableOptions struct {
+	Columns   []string
+	SortBy    string
+	NoHeaders bool
+	Width     int
+}
+
+// PrintTable renders a response as aligned columns. A list response is
+// one row per item of its main array; a single object is printed as field
+// and value pairs.
+func PrintTable(w io.Writer, data json.RawMessage, opts TableOptions) error {
+	v, err := decodeValue(data)
+	if err != nil {
+		// Not JSON; show it as it came.
+		_, err := fmt.Fprintf(w, "%s\n", bytes.TrimSpace(data))
+		return err
+	}
+	key, rows, ok := Rows(v)
+	if !ok {
+		return printRecord(w, record(v), opts)
+	}
+
+	cols := opts.Columns
+	if len(cols) == 0 {
+		cols = defaultColumns(key, rows)
+	}
+	if len(cols) == 0 {
+		return nil
+	}
+	if opts.SortBy != "" {
+		sortRows(rows, opts.SortBy)
+	}
+	cells := make([][]string, 0, len(rows)+1)
+	if !opts.NoHeaders {
+		header := make([]string, len(cols))
+		for i, c := range cols {
+			header[i] = strings.ToUpper(c)
+		}
+		cells = append(cells, header)
+	}
+	for _, row := range rows {
+		line := make([]string, len(cols))
+		for i, c := range cols {
+			line[i] = Cell(Lookup(row, c))
+		}
+		cells = append(cells, line)
+	}
+	return writeAligned(w, cells, opts.Width)
+}
+
+// Rows finds the items of a list response: the response itself when it is
+// an array, otherwise its array field, preferring one named in
+// DefaultColumns, then the longest array of objects. Scalar items become
+// rows with a single "value" column. ok is false when there is no array.
+func Rows(v any) (key string, rows []map[string]any, ok bool) {
+	key, items, ok := listItems(v)
+	if !ok {
+		return "", nil, false
+	}
+	rows = make([]map[string]any, len(items))
+	for i, item := range items {
+		if obj, isObj := item.(map[string]any); isObj {
+			rows[i] = obj
+		} else {
+			rows[i] = map[string]any{"value": item}
+		}
+	}
+	return key, rows, true
+}
+
+func listItems(v any) (key string, items []any, ok bool) {
+	switch v := v.(type) {
+	case []any:
+		return "", v, true
+	case map[string]any:
+		best := -1
+		for k, x := range v {
+			arr, isArr := x.([]any)
+			if !isArr {
+				continue
+			}
+			score := len(arr)
+			if len(arr) > 0 {
+				if _, isObj := arr[0].(map[string]any); isObj {
+					score += 1 << 30
+				}
+			}
+			if _, known := DefaultColumns[k]; known {
+				score += 1 << 40
+			}
+			if score > best || (score == best && k < key) {
+				best, key, items = score, k, arr
+			}
+		}
+		return key, items, best >= 0
+	}
+	return "", nil, false
+}
+
+// record unwraps single-object responses such as {"segment": {...}}.
+func record(v any) any {
+	if obj, ok := v.(map[string]any); ok && len(obj) == 1 {
+		for _, inner := range obj {
+			if innerObj, ok := inner.(map[string]any); ok {
+				return innerObj
+			}
+		}
+	}
+	return v
+}
+
+func printRecord(w io.Writer, v any, opts TableOptions) error {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		_, err := fmt.Fprintln(w, Cell(v))
+		return err
+	}
+	keys := opts.Columns
+	if len(keys) == 0 {
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	cells := make([][]string, len(keys))
+	for i, k := range keys {
+		cells[i] = []string{k, Cell(Lookup(obj, k))}
+	}
+	return writeAligned(w, cells, opts.Width)
+}
+
+func defaultColumns(key string, rows []map[string]any) []string {
+	if cols, ok := DefaultColumns[key]; ok {
+		return cols
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	var cols, rest []string
+	for _, k := range []string{"id", "name"} {
+		if _, ok := rows[0][k]; ok {
+			cols = append(cols, k)
+		}
+	}
+	for k, v := range rows[0] {
+		switch v.(type) {
+		case map[string]any, []any:
+			continue
+		}
+		if k != "id" && k != "name" {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	cols = append(cols, rest...)
+	if len(cols) > maxDefaultColumns {
+		cols = cols[:maxDefaultColumns]
+	}
+	return cols
+}
+
+// Lookup returns the value at a dotted path, trying the whole path as a
+// key first so fields that contain dots still resolve.
+func Lookup(row map[string]any, path string) any {
+	if v, ok := row[path]; ok {
+		return v
+	}
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return nil
+	}
+	inner, ok := row[head].(map[string]any)
+	if !ok {
+		return nil
+	}
+	return Lookup(inner, rest)
+}
+
+// Cell formats a value for one cell: strings as they are, null as empty,
+// objects and arrays as compact JSON, all on one line.
+func Cell(v any) string {
+	return cellReplacer.Replace(text(v))
+}
+
+var cellReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+// text is a value as text: strings as they are, null as empty, objects and
+// arrays as compact JSON.
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+func sortRows(rows []map[string]any, by string) {
+	desc := strings.HasPrefix(by, "-")
+	col := strings.TrimPrefix(by, "-")
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := Lookup(rows[i], col), Lookup(rows[j], col)
+		if a == nil || b == nil {
+			// Missing values go last either way.
+			return a != nil
+		}
+		if desc {
+			a, b = b, a
+		}
+		return less(a, b)
+	})
+}
+
+// less orders numbers numerically and everything else by its cell text.
+func less(a, b any) bool {
+	x, xok := number(a)
+	y, yok := number(b)
+	if xok && yok {
+		return x < y
+	}
+	return Cell(a) < Cell(b)
+}
+
+func number(v any) (float64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// minColumnWidth is as narrow as truncation makes a column.
+const minColumnWidth = 6
+
+func writeAligned(w io.Writer, cells [][]string, width int) error {
+	if len(cells) == 0 {
+		return nil
+	}
+	widths := make([]int, len(cells[0]))
+	for _, line := range cells {
+		for i, c := range line {
+			widths[i] = max(widths[i], utf8.RuneCountInString(c))
+		}
+	}
+	if width > 0 {
+		shrink(widths, width)
+	}
+
+	var buf bytes.Buffer
+	var line strings.Builder
+	for _, row := range cells {
+		line.Reset()
+		for i, c := range row {
+			c = truncate(c, widths[i])
+			line.WriteString(c)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c)+2))
+			}
+		}
+		// Empty trailing cells leave only padding behind.
+		buf.WriteString(strings.TrimRight(line.String(), " "))
+		buf.WriteByte('\n')
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// shrink narrows the widest columns, one character at a time, until the
+// row fits in width or every column is at minColumnWidth.
+func shrink(widths []int, width int) {
+	total := 2 * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	for total > width {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			return
+		}
+		widths[widest]--
+		total--
+	}
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+func decodeValue(data json.RawMessage) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
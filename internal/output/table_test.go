@@ -0,0 +1,87 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+const segmentsPage = `{"segments":[
+	{"id":7,"name":"VIP","type":"manual","state":"finished","tags":["sales"]},
+	{"id":1,"name":"Active Users","type":"dynamic","state":"events","tags":null},
+	{"id":12,"name":"Churned","type":"dynamic"}
+]}`
+
+func renderTable(t *testing.T, data string, opts TableOptions) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := PrintTable(&buf, json.RawMessage(data), opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestPrintTableDefaultColumns(t *testing.T) {
+	got := renderTable(t, segmentsPage, TableOptions{})
+	want := "" +
+		"ID  NAME          TYPE     STATE\n" +
+		"7   VIP           manual   finished\n" +
+		"1   Active Users  dynamic  events\n" +
+		"12  Churned       dynamic\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPrintTableColumnsAndSort(t *testing.T) {
+	got := renderTable(t, segmentsPage, TableOptions{Columns: []string{"name", "id", "tags"}, SortBy: "id", NoHeaders: true})
+	want := "" +
+		"Active Users  1\n" +
+		"VIP           7   [\"sales\"]\n" +
+		"Churned       12\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = renderTable(t, segmentsPage, TableOptions{Columns: []string{"id", "state"}, SortBy: "-state", NoHeaders: true})
+	want = "7   finished\n1   events\n12\n"
+	if got != want {
+		t.Fatalf("descending:\n%s", got)
+	}
+}
+
+func TestPrintTableNestedAndUnknownList(t *testing.T) {
+	data := `{"next":"abc","results":[{"name":"a","id":"x1","metrics":{"sent":3},"kind":"k"}]}`
+	got := renderTable(t, data, TableOptions{})
+	if got != "ID  NAME  KIND\nx1  a     k\n" {
+		t.Fatalf("got:\n%s", got)
+	}
+	got = renderTable(t, data, TableOptions{Columns: []string{"id", "metrics.sent"}})
+	if got != "ID  METRICS.SENT\nx1  3\n" {
+		t.Fatalf("got:\n%s", got)
+	}
+}
+
+func TestPrintTableTruncates(t *testing.T) {
+	data := `[{"id":1,"description":"a rather long description that will not fit"}]`
+	got := renderTable(t, data, TableOptions{Columns: []string{"id", "description"}, Width: 24})
+	want := "ID  DESCRIPTION\n1   a rather long descr…\n"
+	if got != want {
+		t.Fatalf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestPrintTableSingleObject(t *testing.T) {
+	got := renderTable(t, `{"segment":{"id":7,"name":"VIP","tags":["sales"]}}`, TableOptions{})
+	want := "id    7\nname  VIP\ntags  [\"sales\"]\n"
+	if got != want {
+		t.Fatalf("got:\n%s", got)
+	}
+}
+
+func TestPrintTableScalarItems(t *testing.T) {
+	got := renderTable(t, `{"ids":[3,4]}`, TableOptions{})
+	if got != "VALUE\n3\n4\n" {
+		t.Fatalf("got:\n%s", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -18,6 +19,7 @@ type bddContext struct {
 	mux        *http.ServeMux
 	output     string
 	exitCode   int
+	cassette   string
 }
 
 func (b *bddContext) aMockAPIServerIsRunning() error {
@@ -27,6 +29,10 @@ func (b *bddContext) aMockAPIServerIsRunning() error {
 }
 
 func (b *bddContext) theMockServerRespondsToWith(route string, body *godog.DocString) error {
+	return b.theMockServerRespondsToWithStatus(route, http.StatusOK, body)
+}
+
+func (b *bddContext) theMockServerRespondsToWithStatus(route string, status int, body *godog.DocString) error {
 	parts := strings.SplitN(route, " ", 2)
 	if len(parts) != 2 {
 		return fmt.Errorf("invalid route format %q, expected \"METHOD /path\"", route)
@@ -41,24 +47,44 @@ func (b *bddContext) theMockServerRespondsToWith(route string, body *godog.DocSt
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(status)
 		fmt.Fprint(w, responseBody)
 	})
 	return nil
 }
 
+// theCassetteIsLoaded makes later commands replay cassettes/<name>, as
+// recorded with `cio --record`, instead of reaching any server.
+func (b *bddContext) theCassetteIsLoaded(name string) error {
+	dir, err := filepath.Abs(filepath.Join("cassettes", name))
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("cassette %q: %w", name, err)
+	}
+	b.cassette = dir
+	return nil
+}
+
 func (b *bddContext) commandFor(command string) (*exec.Cmd, error) {
 	args := strings.Fields(command)
 	if len(args) == 0 {
 		return nil, fmt.Errorf("empty command")
 	}
 
+	var cmd *exec.Cmd
 	if bin := os.Getenv("CIO_BINARY"); bin != "" {
-		return exec.Command(bin, args[1:]...), nil
+		cmd = exec.Command(bin, args[1:]...)
+	} else {
+		cmd = exec.Command("go", append([]string{"run", ".."}, args[1:]...)...)
 	}
-
-	goArgs := append([]string{"run", ".."}, args[1:]...)
-	return exec.Command("go", goArgs...), nil
+	cmd.Env = os.Environ()
+	if b.cassette != "" {
+		// Replay still resolves credentials; any token will do.
+		cmd.Env = append(cmd.Env, "CIO_REPLAY="+b.cassette, "CUSTOMERIO_API_TOKEN=cassette-token")
+	}
+	return cmd, nil
 }
 
 func (b *bddContext) iRun(command string) error {
@@ -84,7 +110,7 @@ func (b *bddContext) iRunAgainstTheMockServer(command string) error {
 	if err != nil {
 		return err
 	}
-	cmd.Env = append(os.Environ(),
+	cmd.Env = append(cmd.Env,
 		"CIO_BASE_URL="+b.mockServer.URL,
 		"CUSTOMERIO_API_TOKEN=test-token",
 	)
@@ -107,7 +133,7 @@ func (b *bddContext) iRunWithoutAnAPIToken(command string) error {
 		return err
 	}
 	var env []string
-	for _, e := range os.Environ() {
+	for _, e := range cmd.Env {
 		if !strings.HasPrefix(e, "CUSTOMERIO_API_TOKEN=") {
 			env = append(env, e)
 		}
@@ -161,6 +187,8 @@ func InitializeScenario(ctx *godog.ScenarioContext) {
 
 	ctx.Step(`^a mock API server is running$`, b.aMockAPIServerIsRunning)
 	ctx.Step(`^the mock server responds to "([^"]*)" with:$`, b.theMockServerRespondsToWith)
+	ctx.Step(`^the mock server responds to "([^"]*)" with status (\d+):$`, b.theMockServerRespondsToWithStatus)
+	ctx.Step(`^the cassette "([^"]*)" is loaded$`, b.theCassetteIsLoaded)
 	ctx.Step(`^I run "([^"]*)"$`, b.iRun)
 	ctx.Step(`^I run "([^"]*)" against the mock server$`, b.iRunAgainstTheMockServer)
 	ctx.Step(`^I run "([^"]*)" without an API token$`, b.iRunWithoutAnAPIToken)
@@ -169,6 +197,18 @@ func InitializeScenario(ctx *godog.ScenarioContext) {
 }
 
 func TestFeatures(t *testing.T) {
+	// `go run` collapses every non-zero exit status to 1, so build the
+	// binary once when the caller did not provide one.
+	if os.Getenv("CIO_BINARY") == "" {
+		bin := filepath.Join(t.TempDir(), "cio")
+		if out, err := exec.Command("go", "build", "-o", bin, "..").CombinedOutput(); err != nil {
+			t.Fatalf("build cio: %v\n%s", err, out)
+		}
+		t.Setenv("CIO_BINARY", bin)
+	}
+	// Keep the audit log of mutating scenarios out of the real state dir.
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
 	suite := godog.TestSuite{
 		ScenarioInitializer: InitializeScenario,
 		Options: &godog.Options{
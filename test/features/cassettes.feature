@@ -0,0 +1,19 @@
+Feature: Replaying recorded cassettes
+
+  Scenario: List segments from a cassette
+    Given the cassette "segments" is loaded
+    When I run "cio segments ls"
+    Then the exit code should be 0
+    And the output should contain "Active Users"
+
+  Scenario: Get a segment from a cassette
+    Given the cassette "segments" is loaded
+    When I run "cio segments get 7 --jq .segment.tags[0]"
+    Then the exit code should be 0
+    And the output should contain "sales"
+
+  Scenario: Request missing from the cassette
+    Given the cassette "segments" is loaded
+    When I run "cio segments get 99"
+    Then the exit code should be 1
+    And the output should contain "no recorded interaction"
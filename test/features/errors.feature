@@ -0,0 +1,31 @@
+Feature: Error reporting and exit codes
+
+  Background:
+    Given a mock API server is running
+
+  Scenario: Not found exits with code 3
+    Given the mock server responds to "GET /v1/segments/9" with status 404:
+      """
+      {"errors": [{"detail": "segment not found", "status": "404"}]}
+      """
+    When I run "cio segments get 9" against the mock server
+    Then the exit code should be 3
+    And the output should contain "HTTP 404"
+
+  Scenario: Auth failure exits with code 4
+    Given the mock server responds to "GET /v1/segments" with status 401:
+      """
+      {"errors": [{"detail": "unauthorized"}]}
+      """
+    When I run "cio segments ls" against the mock server
+    Then the exit code should be 4
+
+  Scenario: JSON error document with --json
+    Given the mock server responds to "GET /v1/segments/9" with status 404:
+      """
+      {"errors": [{"detail": "segment not found"}]}
+      """
+    When I run "cio segments get 9 --json" against the mock server
+    Then the exit code should be 3
+    And the output should contain "\"message\": \"segment not found\""
+    And the output should contain "\"exit_code\": 3"